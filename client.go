@@ -0,0 +1,72 @@
+package smpp
+
+import (
+	"context"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// ClientConf configures Client.
+type ClientConf struct {
+	// TxBinds is number of transmitter binds. Default is 1.
+	TxBinds int
+	// ReconnectDelay is time to wait before binding again after failed bind
+	// or closed session. Default is 1 second.
+	ReconnectDelay time.Duration
+}
+
+// Client is one logical connection to SMSC made of separate transmitter and
+// receiver binds for peers that don't support transceiver bind. Both halves
+// use credentials from BindConf and are reconnected independently.
+// Requests are sent with the transmitter while requests received by the
+// receiver, e.g. deliver_sm, are served by SessionConf.Handler.
+type Client struct {
+	tx *Pool
+	rx *Pool
+}
+
+// NewClient creates client and starts binding in background. Client must be
+// closed after use.
+func NewClient(sc SessionConf, bc BindConf, cc ClientConf) *Client {
+	txc, rxc := sc, sc
+	if sc.ID != "" {
+		txc.ID, rxc.ID = sc.ID+"-tx", sc.ID+"-rx"
+	}
+	return &Client{
+		tx: NewPool(txc, bc, PoolConf{
+			Size:           cc.TxBinds,
+			Bind:           BindTx,
+			ReconnectDelay: cc.ReconnectDelay,
+		}),
+		rx: NewPool(rxc, bc, PoolConf{
+			Bind:           BindRx,
+			ReconnectDelay: cc.ReconnectDelay,
+		}),
+	}
+}
+
+// Send sends PDU using the transmitter, see Session.Send.
+// Temporary error is returned if the transmitter is not bound.
+func (c *Client) Send(ctx context.Context, req pdu.PDU, opts ...pdu.EncoderOption) (pdu.Header, pdu.PDU, error) {
+	return c.tx.Send(ctx, req, opts...)
+}
+
+// Tx returns bound transmitter session or nil.
+func (c *Client) Tx() *Session {
+	return c.tx.Session()
+}
+
+// Rx returns bound receiver session or nil.
+func (c *Client) Rx() *Session {
+	return c.rx.Session()
+}
+
+// Close unbinds both halves of the client.
+func (c *Client) Close() error {
+	txErr := c.tx.Close()
+	if err := c.rx.Close(); err != nil {
+		return err
+	}
+	return txErr
+}
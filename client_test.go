@@ -0,0 +1,150 @@
+package smpp_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// txRxServer accepts separate transmitter and receiver binds.
+type txRxServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	sessions map[string]*smpp.Session
+	rx       chan *smpp.Session
+}
+
+func startTxRxServer(t *testing.T) *txRxServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &txRxServer{
+		ln:       ln,
+		sessions: make(map[string]*smpp.Session),
+		rx:       make(chan *smpp.Session, 2),
+	}
+	conf := smpp.SessionConf{
+		Type: smpp.SMSC,
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransmitterID:
+				btx, _ := ctx.BindTx()
+				ctx.Respond(btx.Response("SMSC"), pdu.StatusOK)
+			case pdu.BindReceiverID:
+				brx, _ := ctx.BindRx()
+				if err := ctx.Respond(brx.Response("SMSC"), pdu.StatusOK); err == nil {
+					srv.mu.Lock()
+					srv.rx <- srv.sessions[ctx.SessionID()]
+					srv.mu.Unlock()
+				}
+			case pdu.SubmitSmID:
+				sm, _ := ctx.SubmitSm()
+				ctx.Respond(sm.Response("id"), pdu.StatusOK)
+			case pdu.UnbindID:
+				ubd, _ := ctx.Unbind()
+				ctx.Respond(ubd.Response(), pdu.StatusOK)
+			}
+		}),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.mu.Lock()
+			sess := smpp.NewSession(conn, conf)
+			srv.sessions[sess.ID()] = sess
+			srv.mu.Unlock()
+		}
+	}()
+	return srv
+}
+
+func (srv *txRxServer) close() {
+	srv.ln.Close()
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, sess := range srv.sessions {
+		sess.Close()
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := startTxRxServer(t)
+	defer srv.close()
+	delivered := make(chan string, 2)
+	sc := smpp.SessionConf{
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			if ctx.CommandID() != pdu.DeliverSmID {
+				return
+			}
+			sm, err := ctx.DeliverSm()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ctx.Respond(sm.Response(""), pdu.StatusOK)
+			delivered <- string(sm.ShortMessage)
+		}),
+	}
+	c := smpp.NewClient(sc, smpp.BindConf{Addr: srv.ln.Addr().String()}, smpp.ClientConf{
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	deliver := func(rx *smpp.Session, msg string) {
+		t.Helper()
+		sm := &pdu.DeliverSm{SourceAddr: "source", DestinationAddr: "destination", ShortMessage: []byte(msg)}
+		if _, _, err := rx.Send(ctx, sm); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-delivered:
+			if got != msg {
+				t.Errorf("delivered %q expected %q", got, msg)
+			}
+		case <-ctx.Done():
+			t.Fatal("deliver_sm not handled")
+		}
+	}
+	var rx *smpp.Session
+	select {
+	case rx = <-srv.rx:
+	case <-ctx.Done():
+		t.Fatal("receiver not bound")
+	}
+	deliver(rx, "first")
+
+	for c.Tx() == nil {
+		time.Sleep(5 * time.Millisecond)
+	}
+	_, resp, err := c.Send(ctx, &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.(*pdu.SubmitSmResp).MessageID != "id" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	// Receiver is bound again without affecting the transmitter.
+	tx := c.Tx()
+	rx.Close()
+	select {
+	case rx = <-srv.rx:
+	case <-ctx.Done():
+		t.Fatal("receiver not bound again")
+	}
+	deliver(rx, "second")
+	if c.Tx() != tx {
+		t.Error("transmitter bound again")
+	}
+}
@@ -64,15 +64,15 @@ func (ctx *Context) Respond(resp pdu.PDU, status pdu.Status) error {
 		ctx.Sess.mu.Unlock()
 		return err
 	}
-	if _, err := ctx.Sess.enc.Encode(resp, pdu.EncodeStatus(status), pdu.EncodeSeq(ctx.seq)); err != nil {
+	_, written, err := ctx.Sess.enqueue(resp, pdu.EncodeStatus(status), pdu.EncodeSeq(ctx.seq))
+	if err != nil {
 		ctx.Sess.conf.Logger.ErrorF("error encoding pdu: %s %+v", ctx.Sess, err)
 		ctx.Sess.mu.Unlock()
 		return err
 	}
 	ctx.Sess.conf.Logger.DebugF("sent response: %s %s %+v", ctx.Sess, resp.CommandID(), resp)
 	ctx.Sess.mu.Unlock()
-
-	return nil
+	return <-written
 }
 
 // CloseSession will initiate session shutdown after handler returns.
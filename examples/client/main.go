@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
@@ -53,15 +52,16 @@ func main() {
 
 	// test with UDH ------------
 
-	msgWithUdh, _ := hex.DecodeString("0B0504158200000003AA030174657374") // UDH + "test"
+	udh := pdu.UDH{}
+	udh.SetPorts(pdu.Ports{Dst: 0x1582, Src: 0x0000, Wide: true})
+	udh.SetConcat(pdu.Concat{Ref: 0xAA, Total: 3, Seq: 1})
 
 	smWithUdh := &pdu.SubmitSm{
 		SourceAddr:      srcAddr,
 		DestinationAddr: dstAddr,
-		EsmClass: pdu.EsmClass{
-			Feature: pdu.UDHIEsmFeat,
-		},
-		ShortMessage: []byte(msgWithUdh),
+	}
+	if err := smWithUdh.SetUDH(udh, []byte("test")); err != nil {
+		fail("Can't set UDH: %v", err)
 	}
 	_, resp2, err2 := sess.Send(context.Background(), smWithUdh)
 	if err2 != nil {
@@ -0,0 +1,407 @@
+package smpp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// Limiter limits rate of requests sent by the session.
+type Limiter interface {
+	// Wait blocks until request with the command id can be sent or
+	// the context is done.
+	Wait(ctx context.Context, id pdu.CommandID) error
+}
+
+// FeedbackLimiter is Limiter which adjusts the rate according to statuses
+// of the responses. Session calls Observe for every response to its request.
+type FeedbackLimiter interface {
+	Limiter
+	Observe(id pdu.CommandID, status pdu.Status)
+}
+
+// TokenBucket is token bucket rate limiter. Bucket holds up to burst
+// tokens and is refilled with rate tokens per second. Each request takes
+// one token. Rate of zero or less is unlimited.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates full bucket allowing rate requests per second
+// with bursts of up to burst requests.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Must be guarded by mutex.
+func (tb *TokenBucket) refill(now time.Time) {
+	if tb.rate > 0 {
+		tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	}
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+}
+
+// Rate returns number of tokens added per second.
+func (tb *TokenBucket) Rate() float64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.rate
+}
+
+// SetRate changes number of tokens added per second, zero or less is
+// unlimited.
+func (tb *TokenBucket) SetRate(rate float64) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(time.Now())
+	tb.rate = rate
+}
+
+// Allow takes token if one is available without waiting.
+func (tb *TokenBucket) Allow() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if tb.rate <= 0 {
+		return true
+	}
+	tb.refill(time.Now())
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
+
+// Wait takes token waiting for it if necessary. Waiting requests reserve
+// tokens in order of arrival, reservation is returned if the context is
+// done before the token is available.
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	tb.mu.Lock()
+	if tb.rate <= 0 {
+		tb.mu.Unlock()
+		return nil
+	}
+	tb.refill(time.Now())
+	tb.tokens--
+	if tb.tokens >= 0 {
+		tb.mu.Unlock()
+		return nil
+	}
+	delay := time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+	tb.mu.Unlock()
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		tb.mu.Lock()
+		tb.tokens++
+		tb.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// CommandLimiter limits requests by their command id. Command ids can share
+// the bucket to be limited together, e.g. submit_sm and submit_multi.
+// Requests with command id not in the map are not limited.
+type CommandLimiter map[pdu.CommandID]*TokenBucket
+
+// Wait implements Limiter interface.
+func (cl CommandLimiter) Wait(ctx context.Context, id pdu.CommandID) error {
+	if tb, ok := cl[id]; ok {
+		return tb.Wait(ctx)
+	}
+	return nil
+}
+
+type multiLimiter []Limiter
+
+func (ml multiLimiter) Wait(ctx context.Context, id pdu.CommandID) error {
+	for _, l := range ml {
+		if err := l.Wait(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ml multiLimiter) Observe(id pdu.CommandID, status pdu.Status) {
+	for _, l := range ml {
+		if fl, ok := l.(FeedbackLimiter); ok {
+			fl.Observe(id, status)
+		}
+	}
+}
+
+// Limiters combines limiters so the request waits for all of them.
+// Nil limiters are skipped.
+func Limiters(limiters ...Limiter) Limiter {
+	var ml multiLimiter
+	for _, l := range limiters {
+		if l != nil {
+			ml = append(ml, l)
+		}
+	}
+	switch len(ml) {
+	case 0:
+		return nil
+	case 1:
+		return ml[0]
+	}
+	return ml
+}
+
+// LimiterRegistry shares limiters between sessions bound with the same
+// system id so the rate agreed for the account is respected across all
+// of its binds.
+type LimiterRegistry struct {
+	// New creates limiter for the system id the first time it's requested.
+	New func(systemID string) Limiter
+
+	mu       sync.Mutex
+	limiters map[string]Limiter
+}
+
+// Get returns limiter shared by sessions bound with the system id.
+func (r *LimiterRegistry) Get(systemID string) Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.limiters[systemID]; ok {
+		return l
+	}
+	if r.limiters == nil {
+		r.limiters = make(map[string]Limiter)
+	}
+	l := r.New(systemID)
+	r.limiters[systemID] = l
+	return l
+}
+
+// AdaptiveConf configures AdaptiveLimiter.
+type AdaptiveConf struct {
+	// Commands that are limited, usually submit_sm and friends.
+	Commands []pdu.CommandID
+	// MaxRate is the initial and the highest rate in requests per second.
+	// It's required.
+	MaxRate float64
+	// MinRate is the lowest rate the limiter backs off to. Default is 1 or
+	// MaxRate if lower.
+	MinRate float64
+	// Burst of requests allowed by the token bucket. Default is 1.
+	Burst int
+	// Decrease multiplies the rate when the peer throttles. Default is 0.5.
+	Decrease float64
+	// Increase is added to the rate after every IncreaseInterval without
+	// throttling. Default is 10% of MaxRate.
+	Increase float64
+	// IncreaseInterval default is 1 second.
+	IncreaseInterval time.Duration
+	// CoolDown pauses sending after the peer throttles. Default is 1 second.
+	CoolDown time.Duration
+}
+
+// AdaptiveLimiter adjusts the rate using additive increase and multiplicative
+// decrease. Responses with StatusThrottled or StatusMsgQFul, including
+// generic_nack, decrease the rate and pause sending for the cool-down period.
+// After the cool-down rate is increased until throttled again or until it
+// reaches the maximum.
+type AdaptiveLimiter struct {
+	conf     AdaptiveConf
+	bucket   *TokenBucket
+	mu       sync.Mutex
+	rate     float64
+	paused   time.Time
+	increase time.Time
+}
+
+// NewAdaptiveLimiter creates limiter starting at the maximum rate. MaxRate
+// must be positive and not lower than MinRate.
+func NewAdaptiveLimiter(conf AdaptiveConf) (*AdaptiveLimiter, error) {
+	if conf.MaxRate <= 0 {
+		return nil, errors.New("smpp: adaptive limiter without positive MaxRate")
+	}
+	if conf.MinRate <= 0 {
+		conf.MinRate = 1
+		if conf.MaxRate < conf.MinRate {
+			conf.MinRate = conf.MaxRate
+		}
+	}
+	if conf.MaxRate < conf.MinRate {
+		return nil, fmt.Errorf("smpp: adaptive limiter MaxRate %g lower than MinRate %g", conf.MaxRate, conf.MinRate)
+	}
+	if conf.Decrease <= 0 || conf.Decrease >= 1 {
+		conf.Decrease = 0.5
+	}
+	if conf.Increase <= 0 {
+		conf.Increase = conf.MaxRate / 10
+	}
+	if conf.IncreaseInterval == 0 {
+		conf.IncreaseInterval = time.Second
+	}
+	if conf.CoolDown == 0 {
+		conf.CoolDown = time.Second
+	}
+	return &AdaptiveLimiter{
+		conf:     conf,
+		bucket:   NewTokenBucket(conf.MaxRate, conf.Burst),
+		rate:     conf.MaxRate,
+		increase: time.Now(),
+	}, nil
+}
+
+func (al *AdaptiveLimiter) limited(id pdu.CommandID) bool {
+	for _, c := range al.conf.Commands {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Wait implements Limiter interface.
+func (al *AdaptiveLimiter) Wait(ctx context.Context, id pdu.CommandID) error {
+	if !al.limited(id) {
+		return nil
+	}
+	al.mu.Lock()
+	pause := time.Until(al.paused)
+	al.mu.Unlock()
+	if pause > 0 {
+		t := time.NewTimer(pause)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return al.bucket.Wait(ctx)
+}
+
+// Observe implements FeedbackLimiter interface.
+func (al *AdaptiveLimiter) Observe(id pdu.CommandID, status pdu.Status) {
+	throttled := status == pdu.StatusThrottled || status == pdu.StatusMsgQFul
+	if !throttled && (status != pdu.StatusOK || !al.limited(id)) {
+		return
+	}
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	now := time.Now()
+	if throttled {
+		// Responses to requests sent before the back off are ignored.
+		if now.Before(al.paused) {
+			return
+		}
+		al.setRate(al.rate * al.conf.Decrease)
+		al.paused = now.Add(al.conf.CoolDown)
+		al.increase = al.paused
+		return
+	}
+	if al.rate < al.conf.MaxRate && now.Sub(al.increase) >= al.conf.IncreaseInterval {
+		al.setRate(al.rate + al.conf.Increase)
+		al.increase = now
+	}
+}
+
+// Must be guarded by mutex.
+func (al *AdaptiveLimiter) setRate(rate float64) {
+	if rate < al.conf.MinRate {
+		rate = al.conf.MinRate
+	}
+	if rate > al.conf.MaxRate {
+		rate = al.conf.MaxRate
+	}
+	al.rate = rate
+	al.bucket.SetRate(rate)
+}
+
+// Rate returns current rate in requests per second, zero during cool-down.
+func (al *AdaptiveLimiter) Rate() float64 {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if time.Now().Before(al.paused) {
+		return 0
+	}
+	return al.rate
+}
+
+// RequestLimit limits rate of received requests.
+type RequestLimit struct {
+	// Rate of requests per second, zero if unlimited.
+	Rate float64
+	// Burst of requests allowed above the rate. Default is 1.
+	Burst int
+	// Commands that are limited. Default is submit_sm and data_sm.
+	Commands []pdu.CommandID
+}
+
+func (rl RequestLimit) limited(id pdu.CommandID) bool {
+	if rl.Commands == nil {
+		return id == pdu.SubmitSmID || id == pdu.DataSmID
+	}
+	for _, c := range rl.Commands {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (rl RequestLimit) bucket() *TokenBucket {
+	if rl.Rate <= 0 {
+		return nil
+	}
+	return NewTokenBucket(rl.Rate, rl.Burst)
+}
+
+// SystemLimiter limits rate of requests received by all sessions bound
+// with the same system_id.
+type SystemLimiter struct {
+	limit   RequestLimit
+	mu      sync.Mutex
+	buckets map[string]*TokenBucket
+}
+
+// NewSystemLimiter creates limiter applying the limit to each system_id.
+func NewSystemLimiter(limit RequestLimit) *SystemLimiter {
+	return &SystemLimiter{
+		limit:   limit,
+		buckets: make(map[string]*TokenBucket),
+	}
+}
+
+// Allow reports whether request received from the system_id can be
+// processed without exceeding the limit.
+func (sl *SystemLimiter) Allow(systemID string, id pdu.CommandID) bool {
+	if !sl.limit.limited(id) {
+		return true
+	}
+	sl.mu.Lock()
+	tb, ok := sl.buckets[systemID]
+	if !ok {
+		tb = sl.limit.bucket()
+		sl.buckets[systemID] = tb
+	}
+	sl.mu.Unlock()
+	return tb == nil || tb.Allow()
+}
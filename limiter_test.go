@@ -0,0 +1,405 @@
+package smpp_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/internal/mock"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tb := smpp.NewTokenBucket(100, 2)
+	if !tb.Allow() || !tb.Allow() {
+		t.Fatal("burst not allowed")
+	}
+	if tb.Allow() {
+		t.Error("allowed over burst")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := tb.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error got %v", err)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := tb.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("3 tokens at 100/s taken in %s", d)
+	}
+}
+
+func TestTokenBucketUnlimited(t *testing.T) {
+	tb := smpp.NewTokenBucket(0, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if !tb.Allow() {
+			t.Fatal("unlimited bucket denied request")
+		}
+		if err := tb.Wait(ctx); err != nil {
+			t.Fatalf("unlimited bucket wait %v", err)
+		}
+	}
+	tb.SetRate(-1)
+	if err := tb.Wait(ctx); err != nil {
+		t.Errorf("negative rate wait %v", err)
+	}
+	tb.SetRate(1)
+	if !tb.Allow() || tb.Allow() {
+		t.Error("limited bucket doesn't hold burst of one token")
+	}
+}
+
+func TestCommandLimiter(t *testing.T) {
+	submit := smpp.NewTokenBucket(1, 1)
+	l := smpp.Limiters(nil, smpp.CommandLimiter{
+		pdu.SubmitSmID:    submit,
+		pdu.SubmitMultiID: submit,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, pdu.SubmitSmID); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Wait(ctx, pdu.SubmitMultiID); err != context.DeadlineExceeded {
+		t.Errorf("expected submit_multi to share the bucket got %v", err)
+	}
+	if err := l.Wait(context.Background(), pdu.EnquireLinkID); err != nil {
+		t.Errorf("enquire_link limited %v", err)
+	}
+	r := &smpp.LimiterRegistry{New: func(systemID string) smpp.Limiter {
+		return smpp.CommandLimiter{pdu.SubmitSmID: smpp.NewTokenBucket(10, 1)}
+	}}
+	a := r.Get("ESME")
+	if b := r.Get("ESME"); b.(smpp.CommandLimiter)[pdu.SubmitSmID] != a.(smpp.CommandLimiter)[pdu.SubmitSmID] {
+		t.Error("limiter not shared by system id")
+	}
+	if c := r.Get("OTHER"); c.(smpp.CommandLimiter)[pdu.SubmitSmID] == a.(smpp.CommandLimiter)[pdu.SubmitSmID] {
+		t.Error("limiter shared by different system ids")
+	}
+}
+
+func TestSessionLimiter(t *testing.T) {
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteWrite(e.i(&pdu.BindTRx{})).ByteRead(e.s(&pdu.BindTRxResp{})).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id1"))).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id2"))).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id3"))).
+		Closed()
+	submitRate := smpp.NewTokenBucket(50, 1)
+	sess := smpp.NewSession(conn, smpp.SessionConf{
+		Limiter: smpp.CommandLimiter{pdu.SubmitSmID: submitRate},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, &pdu.BindTRx{}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, _, err := sess.Send(ctx, submitSm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Errorf("3 submit_sm at 50/s sent in %s", d)
+	}
+	// Slow the bucket down and take token refilled since the last submit_sm
+	// so that the next one can't be sent before the deadline.
+	submitRate.SetRate(1.0 / 3600)
+	submitRate.Allow()
+	shortCtx, shortCancel := context.WithTimeout(ctx, time.Millisecond)
+	defer shortCancel()
+	if _, _, err := sess.Send(shortCtx, submitSm); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error while waiting for limiter got %v", err)
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	al, err := smpp.NewAdaptiveLimiter(smpp.AdaptiveConf{
+		Commands:         []pdu.CommandID{pdu.SubmitSmID},
+		MaxRate:          100,
+		MinRate:          10,
+		Increase:         20,
+		IncreaseInterval: 50 * time.Millisecond,
+		CoolDown:         20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := al.Rate(); r != 100 {
+		t.Errorf("initial rate %f", r)
+	}
+	al.Observe(pdu.SubmitSmID, pdu.StatusThrottled)
+	al.Observe(pdu.SubmitSmID, pdu.StatusMsgQFul)
+	if r := al.Rate(); r != 0 {
+		t.Errorf("rate during cool-down %f", r)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := al.Wait(ctx, pdu.SubmitSmID); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error during cool-down got %v", err)
+	}
+	if err := al.Wait(ctx, pdu.EnquireLinkID); err != nil {
+		t.Errorf("enquire_link limited during cool-down %v", err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if r := al.Rate(); r != 50 {
+		t.Errorf("rate after back off %f", r)
+	}
+	al.Observe(pdu.SubmitSmID, pdu.StatusOK)
+	if r := al.Rate(); r != 50 {
+		t.Errorf("rate increased before interval %f", r)
+	}
+	time.Sleep(50 * time.Millisecond)
+	al.Observe(pdu.SubmitSmID, pdu.StatusOK)
+	if r := al.Rate(); r != 70 {
+		t.Errorf("rate after increase %f", r)
+	}
+	al.Observe(pdu.GenericNackID, pdu.StatusThrottled)
+	time.Sleep(25 * time.Millisecond)
+	if r := al.Rate(); r != 35 {
+		t.Errorf("rate after generic_nack %f", r)
+	}
+}
+
+func TestAdaptiveLimiterConf(t *testing.T) {
+	for _, conf := range []smpp.AdaptiveConf{
+		{},
+		{MaxRate: -1},
+		{MaxRate: 5, MinRate: 10},
+	} {
+		if _, err := smpp.NewAdaptiveLimiter(conf); err == nil {
+			t.Errorf("expected error for MaxRate %g MinRate %g", conf.MaxRate, conf.MinRate)
+		}
+	}
+	al, err := smpp.NewAdaptiveLimiter(smpp.AdaptiveConf{MaxRate: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := al.Rate(); r != 0.5 {
+		t.Errorf("initial rate %f", r)
+	}
+}
+
+func TestSessionAdaptiveLimiter(t *testing.T) {
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteWrite(e.i(&pdu.BindTRx{})).ByteRead(e.s(&pdu.BindTRxResp{})).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response(""), pdu.StatusThrottled)).
+		Closed()
+	al, err := smpp.NewAdaptiveLimiter(smpp.AdaptiveConf{
+		Commands: []pdu.CommandID{pdu.SubmitSmID},
+		MaxRate:  100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := smpp.NewSession(conn, smpp.SessionConf{Limiter: smpp.Limiters(al)})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, &pdu.BindTRx{}); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = sess.Send(ctx, submitSm)
+	if serr, ok := err.(smpp.StatusError); !ok || serr.Status() != pdu.StatusThrottled {
+		t.Errorf("expected throttled status got %v", err)
+	}
+	if r := al.Rate(); r != 0 {
+		t.Errorf("limiter not paused after throttled response, rate %f", r)
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestSystemLimiter(t *testing.T) {
+	sl := smpp.NewSystemLimiter(smpp.RequestLimit{Rate: 1})
+	if !sl.Allow("ESME", pdu.SubmitSmID) {
+		t.Error("first submit_sm not allowed")
+	}
+	if sl.Allow("ESME", pdu.DataSmID) {
+		t.Error("data_sm over limit allowed")
+	}
+	if !sl.Allow("ESME", pdu.EnquireLinkID) {
+		t.Error("enquire_link limited")
+	}
+	if !sl.Allow("OTHER", pdu.SubmitSmID) {
+		t.Error("other system_id limited")
+	}
+}
+
+func TestSMSCSessionRequestLimit(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	bindTRxResp := bindTRx.Response("SMSC")
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	done := make(chan struct{})
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteRead(e.i(bindTRx)).ByteWrite(e.s(bindTRxResp)).
+		ByteRead(e.i(submitSm)).ByteWrite(e.s(submitSm.Response("id1"))).Wait(1).
+		ByteRead(e.i(submitSm)).ByteWrite(e.s(&pdu.SubmitSmResp{}, pdu.StatusThrottled)).Wait(2).
+		ByteRead(e.i(pdu.EnquireLink{})).ByteWrite(e.s(pdu.EnquireLinkResp{})).Wait(3).
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{
+		Type:          smpp.SMSC,
+		RequestLimit:  smpp.RequestLimit{Rate: 1},
+		SystemLimiter: smpp.NewSystemLimiter(smpp.RequestLimit{Rate: 100, Burst: 10}),
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransceiverID:
+				ctx.Respond(bindTRxResp, pdu.StatusOK)
+			case pdu.SubmitSmID:
+				ctx.Respond(submitSm.Response("id1"), pdu.StatusOK)
+			case pdu.EnquireLinkID:
+				ctx.Respond(pdu.EnquireLinkResp{}, pdu.StatusOK)
+				close(done)
+			}
+		}),
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for enquire_link")
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestSMSCSessionSystemLimiter(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	bindTRxResp := bindTRx.Response("SMSC")
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	sl := smpp.NewSystemLimiter(smpp.RequestLimit{Rate: 1.0 / 3600})
+	// Limit of session's own system_id must not affect the peer.
+	sl.Allow("SMSC", pdu.SubmitSmID)
+	done := make(chan struct{})
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteRead(e.i(bindTRx)).ByteWrite(e.s(bindTRxResp)).
+		ByteRead(e.i(submitSm)).ByteWrite(e.s(submitSm.Response("id1"))).Wait(1).
+		ByteRead(e.i(submitSm)).ByteWrite(e.s(&pdu.SubmitSmResp{}, pdu.StatusThrottled)).Wait(2).
+		ByteRead(e.i(pdu.EnquireLink{})).ByteWrite(e.s(pdu.EnquireLinkResp{})).Wait(3).
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{
+		Type:          smpp.SMSC,
+		SystemID:      "SMSC",
+		SystemLimiter: sl,
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransceiverID:
+				ctx.Respond(bindTRxResp, pdu.StatusOK)
+			case pdu.SubmitSmID:
+				ctx.Respond(submitSm.Response("id1"), pdu.StatusOK)
+			case pdu.EnquireLinkID:
+				ctx.Respond(pdu.EnquireLinkResp{}, pdu.StatusOK)
+				close(done)
+			}
+		}),
+	})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for enquire_link")
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestSMSCSessionRequestWindow(t *testing.T) {
+	handling := make(chan struct{}, 1)
+	release := make(chan struct{})
+	client, server := net.Pipe()
+	defer client.Close()
+	sess := smpp.NewSession(server, smpp.SessionConf{
+		Type:       smpp.SMSC,
+		ReqWinSize: 1,
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransceiverID:
+				btrx, _ := ctx.BindTRx()
+				ctx.Respond(btrx.Response("SMSC"), pdu.StatusOK)
+			case pdu.SubmitSmID:
+				handling <- struct{}{}
+				<-release
+				sm, _ := ctx.SubmitSm()
+				ctx.Respond(sm.Response("id"), pdu.StatusOK)
+			}
+		}),
+	})
+	defer sess.Close()
+	resps := make(chan pdu.Header, 1)
+	go func() {
+		dec := pdu.NewDecoder(client)
+		for {
+			h, _, err := dec.Decode()
+			if err != nil {
+				close(resps)
+				return
+			}
+			resps <- h
+		}
+	}()
+	expect := func(seq uint32, id pdu.CommandID, status pdu.Status) {
+		t.Helper()
+		select {
+		case h := <-resps:
+			if h.Sequence() != seq || h.CommandID() != id || h.Status() != status {
+				t.Errorf("expected %d %s %s got %d %s %s", seq, id, status, h.Sequence(), h.CommandID(), h.Status())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %d %s", seq, id)
+		}
+	}
+	enc := pdu.NewEncoder(client, nil)
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	enc.Encode(&pdu.BindTRx{SystemID: "ESME"})
+	expect(1, pdu.BindTransceiverRespID, pdu.StatusOK)
+	// Bind holds the window until its handler returns, retry until the
+	// submit_sm is handled.
+	var held uint32
+	for held == 0 {
+		seq, _ := enc.Encode(submitSm)
+		select {
+		case <-handling:
+			held = seq
+		case h := <-resps:
+			if h.Sequence() != seq || h.Status() != pdu.StatusThrottled {
+				t.Fatalf("unexpected response %d %s %s", h.Sequence(), h.CommandID(), h.Status())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for submit_sm")
+		}
+	}
+	seq, _ := enc.Encode(submitSm)
+	expect(seq, pdu.SubmitSmRespID, pdu.StatusThrottled)
+	close(release)
+	expect(held, pdu.SubmitSmRespID, pdu.StatusOK)
+}
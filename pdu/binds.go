@@ -1,9 +1,5 @@
 package pdu
 
-import (
-	"fmt"
-)
-
 // BindTx binding pdu in transmitter mode.
 type BindTx struct {
 	SystemID         string
@@ -20,6 +16,11 @@ func (p BindTx) CommandID() CommandID {
 	return BindTransmitterID
 }
 
+// Validate implements pdu.Validator interface.
+func (p BindTx) Validate() error {
+	return validateBind(p.SystemID, p.Password, p.SystemType, p.AddressRange)
+}
+
 // Response creates new BindTxResp.
 func (p BindTx) Response(sysID string) *BindTxResp {
 	return &BindTxResp{
@@ -29,7 +30,13 @@ func (p BindTx) Response(sysID string) *BindTxResp {
 
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p BindTx) MarshalBinary() ([]byte, error) {
-	return marshalBind(
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p BindTx) AppendBinary(dst []byte) ([]byte, error) {
+	return appendBind(
+		dst,
 		p.SystemID,
 		p.Password,
 		p.SystemType,
@@ -65,9 +72,19 @@ func (p BindTxResp) CommandID() CommandID {
 	return BindTransmitterRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p BindTxResp) Validate() error {
+	return validateSystemIDResp(BindTransmitterRespID, p.SystemID, p.Options)
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p BindTxResp) MarshalBinary() ([]byte, error) {
-	return cStringOptsRespMarshal(p.SystemID, p.Options)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p BindTxResp) AppendBinary(dst []byte) ([]byte, error) {
+	return cStringOptsRespAppend(dst, p.SystemID, p.Options)
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
@@ -93,6 +110,11 @@ func (p BindRx) CommandID() CommandID {
 	return BindReceiverID
 }
 
+// Validate implements pdu.Validator interface.
+func (p BindRx) Validate() error {
+	return validateBind(p.SystemID, p.Password, p.SystemType, p.AddressRange)
+}
+
 // Response creates new BindRxResp.
 func (p BindRx) Response(sysID string) *BindRxResp {
 	return &BindRxResp{
@@ -102,7 +124,13 @@ func (p BindRx) Response(sysID string) *BindRxResp {
 
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p BindRx) MarshalBinary() ([]byte, error) {
-	return marshalBind(
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p BindRx) AppendBinary(dst []byte) ([]byte, error) {
+	return appendBind(
+		dst,
 		p.SystemID,
 		p.Password,
 		p.SystemType,
@@ -138,9 +166,19 @@ func (p BindRxResp) CommandID() CommandID {
 	return BindReceiverRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p BindRxResp) Validate() error {
+	return validateSystemIDResp(BindReceiverRespID, p.SystemID, p.Options)
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p BindRxResp) MarshalBinary() ([]byte, error) {
-	return cStringOptsRespMarshal(p.SystemID, p.Options)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p BindRxResp) AppendBinary(dst []byte) ([]byte, error) {
+	return cStringOptsRespAppend(dst, p.SystemID, p.Options)
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
@@ -166,6 +204,11 @@ func (p BindTRx) CommandID() CommandID {
 	return BindTransceiverID
 }
 
+// Validate implements pdu.Validator interface.
+func (p BindTRx) Validate() error {
+	return validateBind(p.SystemID, p.Password, p.SystemType, p.AddressRange)
+}
+
 // Response creates new BindTRxResp.
 func (p BindTRx) Response(sysID string) *BindTRxResp {
 	return &BindTRxResp{
@@ -175,7 +218,13 @@ func (p BindTRx) Response(sysID string) *BindTRxResp {
 
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p BindTRx) MarshalBinary() ([]byte, error) {
-	return marshalBind(
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p BindTRx) AppendBinary(dst []byte) ([]byte, error) {
+	return appendBind(
+		dst,
 		p.SystemID,
 		p.Password,
 		p.SystemType,
@@ -211,9 +260,19 @@ func (p BindTRxResp) CommandID() CommandID {
 	return BindTransceiverRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p BindTRxResp) Validate() error {
+	return validateSystemIDResp(BindTransceiverRespID, p.SystemID, p.Options)
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p BindTRxResp) MarshalBinary() ([]byte, error) {
-	return cStringOptsRespMarshal(p.SystemID, p.Options)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p BindTRxResp) AppendBinary(dst []byte) ([]byte, error) {
+	return cStringOptsRespAppend(dst, p.SystemID, p.Options)
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
@@ -223,53 +282,52 @@ func (p *BindTRxResp) UnmarshalBinary(body []byte) error {
 	return err
 }
 
-func marshalBind(systemID, password, systemType string, interfaceVer, addrTon, addrNpi int, addrRange string) ([]byte, error) {
-	out := append([]byte(systemID), 0)
-	out = append(out, append([]byte(password), 0)...)
-	out = append(out, append([]byte(systemType), 0)...)
+func appendBind(dst []byte, systemID, password, systemType string, interfaceVer, addrTon, addrNpi int, addrRange string) ([]byte, error) {
+	out := appendCString(dst, systemID)
+	out = appendCString(out, password)
+	out = appendCString(out, systemType)
 	out = append(out, byte(interfaceVer), byte(addrTon), byte(addrNpi))
-	out = append(out, append([]byte(addrRange), 0)...)
-	return out, nil
+	return appendCString(out, addrRange), nil
 }
 
 func unmarshalBind(body []byte, systemID, password, systemType *string, interfaceVer, addrTon, addrNpi *int, addrRange *string) error {
 	if len(body) < 7 {
-		return fmt.Errorf("smpp/pdu: bind body too short: %d", len(body))
+		return bodyTooShort("bind", len(body))
 	}
 	buf := newBuffer(body)
 	res, err := buf.ReadCString(16)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding system_id %s", err)
+		return decodeError(SystemIDFld, err)
 	}
 	*systemID = string(res)
 	res, err = buf.ReadCString(9)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding password %s", err)
+		return decodeError(PasswordFld, err)
 	}
 	*password = string(res)
 	res, err = buf.ReadCString(13)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding system_type %s", err)
+		return decodeError(SystemTypeFld, err)
 	}
 	*systemType = string(res)
 	b, err := buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding interface_version %s", err)
+		return decodeError(InterfaceVersionFld, err)
 	}
 	*interfaceVer = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding addr_ton %s", err)
+		return decodeError(AddrTonFld, err)
 	}
 	*addrTon = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding addr_npi %s", err)
+		return decodeError(AddrNpiFld, err)
 	}
 	*addrNpi = int(b)
 	res, err = buf.ReadCString(41)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding addr_range %s", err)
+		return decodeError(AddressRangeFld, err)
 	}
 	*addrRange = string(res)
 	return nil
@@ -1,6 +1,6 @@
 package pdu
 
-//go:generate stringer -type=Status,CommandID,TagID
+//go:generate stringer -type=Status,CommandID
 
 const (
 	// MaxPDUSize is maximal size of the PDU in bytes.
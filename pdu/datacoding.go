@@ -0,0 +1,179 @@
+package pdu
+
+// DataCoding represents data_coding field which is interpreted as GSM 03.38
+// data coding scheme. Values 0x00-0x0F have SMPP specific meaning.
+// See more: https://en.wikipedia.org/wiki/Data_Coding_Scheme
+type DataCoding uint8
+
+// SMPP data coding values.
+const (
+	DefaultDataCoding   DataCoding = 0x00 // SMSC Default Alphabet
+	IA5DataCoding       DataCoding = 0x01 // IA5 (CCITT T.50)/ASCII (ANSI X3.4)
+	BinaryDataCoding    DataCoding = 0x02 // Octet unspecified (8-bit binary)
+	Latin1DataCoding    DataCoding = 0x03 // Latin 1 (ISO-8859-1)
+	OctetDataCoding     DataCoding = 0x04 // Octet unspecified (8-bit binary)
+	JISDataCoding       DataCoding = 0x05 // JIS (X 0208-1990)
+	CyrillicDataCoding  DataCoding = 0x06 // Cyrillic (ISO-8859-5)
+	HebrewDataCoding    DataCoding = 0x07 // Latin/Hebrew (ISO-8859-8)
+	UCS2DataCoding      DataCoding = 0x08 // UCS2 (ISO/IEC-10646)
+	PictogramDataCoding DataCoding = 0x09 // Pictogram Encoding
+	ISO2022JPDataCoding DataCoding = 0x0A // ISO-2022-JP (Music Codes)
+	KanjiDataCoding     DataCoding = 0x0D // Extended Kanji JIS(X 0212-1990)
+	KSC5601DataCoding   DataCoding = 0x0E // KS C 5601
+)
+
+// Alphabet is the character set used for encoding short message.
+type Alphabet int
+
+// Alphabets that can be specified by data coding.
+const (
+	GSM7Alphabet Alphabet = iota // GSM 7 bit default alphabet
+	OctetAlphabet
+	UCS2Alphabet
+	ReservedAlphabet
+	IA5Alphabet
+	Latin1Alphabet
+	JISAlphabet
+	CyrillicAlphabet
+	HebrewAlphabet
+	PictogramAlphabet
+	ISO2022JPAlphabet
+	KanjiAlphabet
+	KSC5601Alphabet
+)
+
+// MessageClass defines how the receiving mobile should handle the message.
+type MessageClass int
+
+// Message classes.
+const (
+	NoMessageClass MessageClass = iota
+	Class0                      // Flash message, displayed immediately and not stored.
+	Class1                      // Mobile equipment specific.
+	Class2                      // SIM specific.
+	Class3                      // Terminal equipment specific.
+)
+
+// MessageWaiting describes message waiting indication.
+type MessageWaiting struct {
+	Type   IndicationType
+	Active bool // Set indication active or inactive.
+	Store  bool // Store the message, otherwise it may be discarded.
+	Count  int  // Number of waiting messages, only carried by UDH indication.
+}
+
+var smppAlphabets = [16]Alphabet{
+	GSM7Alphabet, IA5Alphabet, OctetAlphabet, Latin1Alphabet,
+	OctetAlphabet, JISAlphabet, CyrillicAlphabet, HebrewAlphabet,
+	UCS2Alphabet, PictogramAlphabet, ISO2022JPAlphabet, ReservedAlphabet,
+	ReservedAlphabet, KanjiAlphabet, KSC5601Alphabet, ReservedAlphabet,
+}
+
+// NewDataCoding creates data coding with given alphabet and message class.
+// Message class can only be combined with GSM7, octet and UCS2 alphabets
+// and it's ignored for others.
+func NewDataCoding(a Alphabet, c MessageClass) DataCoding {
+	var bits DataCoding
+	switch a {
+	case GSM7Alphabet:
+	case OctetAlphabet:
+		bits = 0x01
+	case UCS2Alphabet:
+		bits = 0x02
+	default:
+		for i, sa := range smppAlphabets {
+			if sa == a {
+				return DataCoding(i)
+			}
+		}
+		return DefaultDataCoding
+	}
+	if c == NoMessageClass {
+		return bits << 2
+	}
+	return 0x10 | bits<<2 | DataCoding(c-Class0)
+}
+
+// FlashDataCoding creates data coding for flash (class 0) messages.
+func FlashDataCoding(a Alphabet) DataCoding {
+	return NewDataCoding(a, Class0)
+}
+
+// MessageWaitingDataCoding creates data coding from message waiting
+// indication group. UCS2 messages are always stored.
+func MessageWaitingDataCoding(mw MessageWaiting, ucs2 bool) DataCoding {
+	dc := DataCoding(0xC0)
+	switch {
+	case ucs2:
+		dc = 0xE0
+	case mw.Store:
+		dc = 0xD0
+	}
+	if mw.Active {
+		dc |= 0x08
+	}
+	return dc | DataCoding(mw.Type&0x03)
+}
+
+// Alphabet returns alphabet used for the message.
+func (dc DataCoding) Alphabet() Alphabet {
+	switch dc >> 4 {
+	case 0x0:
+		return smppAlphabets[dc]
+	case 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7:
+		return Alphabet((dc >> 2) & 0x03)
+	case 0xC, 0xD:
+		return GSM7Alphabet
+	case 0xE:
+		return UCS2Alphabet
+	case 0xF:
+		if dc&0x04 != 0 {
+			return OctetAlphabet
+		}
+		return GSM7Alphabet
+	}
+	return ReservedAlphabet
+}
+
+// Class returns message class if data coding specifies it.
+func (dc DataCoding) Class() MessageClass {
+	switch {
+	case dc>>6 == 0x0, dc>>6 == 0x1:
+		if dc&0x10 == 0 {
+			return NoMessageClass
+		}
+	case dc>>4 != 0xF:
+		return NoMessageClass
+	}
+	return Class0 + MessageClass(dc&0x03)
+}
+
+// IsFlash returns true for class 0 messages.
+func (dc DataCoding) IsFlash() bool {
+	return dc.Class() == Class0
+}
+
+// Compressed returns true if message text is compressed.
+func (dc DataCoding) Compressed() bool {
+	return dc>>6 <= 0x1 && dc&0x20 != 0
+}
+
+// AutoDelete returns true if message is marked for automatic deletion
+// after it's read.
+func (dc DataCoding) AutoDelete() bool {
+	return dc>>6 == 0x1
+}
+
+// MessageWaiting returns message waiting indication if data coding
+// belongs to one of message waiting indication groups.
+func (dc DataCoding) MessageWaiting() (MessageWaiting, bool) {
+	group := dc >> 4
+	if group < 0xC || group > 0xE {
+		return MessageWaiting{}, false
+	}
+	return MessageWaiting{
+		Type:   IndicationType(dc & 0x03),
+		Active: dc&0x08 != 0,
+		Store:  group != 0xC,
+	}, true
+}
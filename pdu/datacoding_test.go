@@ -0,0 +1,64 @@
+package pdu
+
+import "testing"
+
+func TestDataCoding(t *testing.T) {
+	tt := []struct {
+		dc         DataCoding
+		alphabet   Alphabet
+		class      MessageClass
+		compressed bool
+		mwi        bool
+	}{
+		{DefaultDataCoding, GSM7Alphabet, NoMessageClass, false, false},
+		{Latin1DataCoding, Latin1Alphabet, NoMessageClass, false, false},
+		{UCS2DataCoding, UCS2Alphabet, NoMessageClass, false, false},
+		{0x10, GSM7Alphabet, Class0, false, false},
+		{0x18, UCS2Alphabet, Class0, false, false},
+		{0x16, OctetAlphabet, Class2, false, false},
+		{0x32, GSM7Alphabet, Class2, true, false},
+		{0x48, UCS2Alphabet, NoMessageClass, false, false},
+		{0x80, ReservedAlphabet, NoMessageClass, false, false},
+		{0xC8, GSM7Alphabet, NoMessageClass, false, true},
+		{0xE0, UCS2Alphabet, NoMessageClass, false, true},
+		{0xF0, GSM7Alphabet, Class0, false, false},
+		{0xF6, OctetAlphabet, Class2, false, false},
+	}
+	for _, row := range tt {
+		if a := row.dc.Alphabet(); a != row.alphabet {
+			t.Errorf("0x%02X Alphabet() => %d expected %d", row.dc, a, row.alphabet)
+		}
+		if c := row.dc.Class(); c != row.class {
+			t.Errorf("0x%02X Class() => %d expected %d", row.dc, c, row.class)
+		}
+		if c := row.dc.Compressed(); c != row.compressed {
+			t.Errorf("0x%02X Compressed() => %v expected %v", row.dc, c, row.compressed)
+		}
+		if _, ok := row.dc.MessageWaiting(); ok != row.mwi {
+			t.Errorf("0x%02X MessageWaiting() => %v expected %v", row.dc, ok, row.mwi)
+		}
+	}
+}
+
+func TestNewDataCoding(t *testing.T) {
+	if dc := FlashDataCoding(GSM7Alphabet); dc != 0x10 || !dc.IsFlash() {
+		t.Errorf("flash gsm7 0x%02X", dc)
+	}
+	if dc := FlashDataCoding(UCS2Alphabet); dc != 0x18 {
+		t.Errorf("flash ucs2 0x%02X", dc)
+	}
+	if dc := NewDataCoding(OctetAlphabet, Class2); dc != 0x16 {
+		t.Errorf("sim octet 0x%02X", dc)
+	}
+	if dc := NewDataCoding(Latin1Alphabet, Class0); dc != Latin1DataCoding {
+		t.Errorf("latin1 0x%02X", dc)
+	}
+	mw := MessageWaiting{Type: FaxIndication, Active: true, Store: true}
+	dc := MessageWaitingDataCoding(mw, false)
+	if dc != 0xD9 {
+		t.Errorf("mwi 0x%02X", dc)
+	}
+	if got, ok := dc.MessageWaiting(); !ok || got != mw {
+		t.Errorf("mwi decoded %+v", got)
+	}
+}
@@ -0,0 +1,103 @@
+package pdu
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ValidationError describes invalid PDU field together with the command
+// status that should be reported to the peer.
+type ValidationError struct {
+	Field  string
+	Status Status
+	Reason string
+}
+
+// Error implements error interface.
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("smpp/pdu: invalid %s: %s", e.Field, e.Reason)
+}
+
+// ErrorStatus returns command status carried by the error. If error doesn't
+// carry any status ok is false.
+func ErrorStatus(err error) (Status, bool) {
+	var berr BodyError
+	if errors.As(err, &berr) {
+		return berr.Status, true
+	}
+	var verr ValidationError
+	if errors.As(err, &verr) {
+		return verr.Status, true
+	}
+	return StatusOK, false
+}
+
+// BodyError is returned by Decoder when PDU was read completely but its
+// body couldn't be decoded. Connection is still in sync and the peer can
+// be responded with the Status.
+type BodyError struct {
+	Err    error
+	Status Status
+}
+
+// Error implements error interface.
+func (e BodyError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns underlying decoding error.
+func (e BodyError) Unwrap() error {
+	return e.Err
+}
+
+// LengthError is returned by Decoder when command_length exceeds
+// MaxDiscardLength. Body isn't read so the connection is out of sync, the
+// peer should be responded with generic_nack and StatusInvCmdLen and the
+// connection closed.
+type LengthError struct {
+	Length uint32
+}
+
+// Error implements error interface.
+func (e LengthError) Error() string {
+	return fmt.Sprintf("smpp/pdu: command_length %d over limit %d", e.Length, MaxDiscardLength)
+}
+
+var fieldStatus = map[string]Status{
+	SystemIDFld:             StatusInvSysID,
+	PasswordFld:             StatusInvPaswd,
+	SystemTypeFld:           StatusInvSysTyp,
+	ServiceTypeFld:          StatusInvSerTyp,
+	SourceAddrFld:           StatusInvSrcAdr,
+	DestinationAddrFld:      StatusInvDstAdr,
+	EsmClassFld:             StatusInvEsmClass,
+	PriorityFlagFld:         StatusInvPrtFlg,
+	ScheduleDeliveryTimeFld: StatusInvSched,
+	ValidityPeriodFld:       StatusInvExpiry,
+	RegisteredDeliveryFld:   StatusInvRegDlvFlg,
+	ReplaceIfPresentFlagFld: StatusInvRepFlag,
+	ShortMessageFld:         StatusInvMsgLen,
+	MessageIDFld:            StatusInvMsgID,
+}
+
+// decodeError wraps error of decoding mandatory field. Truncated body is
+// reported with StatusInvCmdLen, other errors with the field status.
+func decodeError(field string, err error) error {
+	status, ok := fieldStatus[field]
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		status = StatusInvCmdLen
+	case !ok:
+		status = StatusSysErr
+	}
+	return ValidationError{Field: field, Status: status, Reason: err.Error()}
+}
+
+func bodyTooShort(name string, l int) error {
+	return ValidationError{
+		Field:  name,
+		Status: StatusInvCmdLen,
+		Reason: fmt.Sprintf("body too short: %d", l),
+	}
+}
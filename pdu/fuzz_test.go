@@ -0,0 +1,98 @@
+//go:build go1.18
+// +build go1.18
+
+package pdu
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+var fuzzCommands = []CommandID{
+	GenericNackID,
+	BindReceiverID,
+	BindReceiverRespID,
+	BindTransmitterID,
+	BindTransmitterRespID,
+	QuerySmID,
+	QuerySmRespID,
+	SubmitSmID,
+	SubmitSmRespID,
+	DeliverSmID,
+	DeliverSmRespID,
+	UnbindID,
+	UnbindRespID,
+	ReplaceSmID,
+	ReplaceSmRespID,
+	CancelSmID,
+	CancelSmRespID,
+	BindTransceiverID,
+	BindTransceiverRespID,
+	OutbindID,
+	EnquireLinkID,
+	EnquireLinkRespID,
+	SubmitMultiID,
+	SubmitMultiRespID,
+	AlertNotificationID,
+	DataSmID,
+	DataSmRespID,
+}
+
+func FuzzUnmarshalBinary(f *testing.F) {
+	for _, row := range pduTT {
+		b, _ := hex.DecodeString(toHexStr(row.hexStr))
+		for i, id := range fuzzCommands {
+			if id == row.pdu.CommandID() {
+				f.Add(uint8(i), b)
+			}
+		}
+	}
+	f.Fuzz(func(t *testing.T, i uint8, body []byte) {
+		p, ok := newPDU(fuzzCommands[int(i)%len(fuzzCommands)])
+		if !ok {
+			t.Fatalf("command %d not supported", i)
+		}
+		if err := p.UnmarshalBinary(body); err != nil {
+			return
+		}
+		if v, ok := p.(Validator); ok {
+			v.Validate()
+		}
+		p.MarshalBinary()
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, row := range codingTT {
+		b, _ := hex.DecodeString(toHexStr(row.headerHex + pduTT[row.pduIndex].hexStr))
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		dec := NewDecoder(bytes.NewReader(b), DecodeMaxLength(1024))
+		for {
+			if _, _, err := dec.Decode(); err != nil {
+				if _, ok := ErrorStatus(err); !ok {
+					return
+				}
+			}
+		}
+	})
+}
+
+func FuzzParseUDH(f *testing.F) {
+	b, _ := hex.DecodeString("150504158200000003AA03010102800324010A4201FF74657374")
+	f.Add(b)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		udh, _, err := ParseUDH(b)
+		if err != nil {
+			return
+		}
+		udh.Concat()
+		udh.Ports()
+		udh.SpecialSMS()
+		if _, err := udh.MarshalBinary(); err != nil {
+			t.Errorf("marshal parsed udh %X: %v", b, err)
+		}
+	})
+}
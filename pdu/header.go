@@ -40,15 +40,15 @@ func (h header) Sequence() uint32 {
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (h *header) UnmarshalBinary(body []byte) error {
-	h.length = binary.BigEndian.Uint32(body[:4])
-	if h.length < 16 {
-		return errors.New("smpp: pdu length under lower limit")
-	}
-	if h.length > MaxPDUSize {
-		return errors.New("smpp: pdu length over upper limit")
+	if len(body) < 16 {
+		return errors.New("smpp: pdu header too short")
 	}
+	h.length = binary.BigEndian.Uint32(body[:4])
 	h.commandID = CommandID(binary.BigEndian.Uint32(body[4:8]))
 	h.status = Status(binary.BigEndian.Uint32(body[8:12]))
 	h.sequence = binary.BigEndian.Uint32(body[12:16])
+	if h.length < 16 {
+		return errors.New("smpp: pdu length under lower limit")
+	}
 	return nil
 }
@@ -0,0 +1,96 @@
+package pdu
+
+// Message waiting indication can be carried in three different ways, data
+// coding scheme group, special SMS message indication UDH element or
+// ms_msg_wait_facilities option. Handsets and networks differ in which one
+// they support so helpers are provided for all of them.
+
+// SetMessageWaitingDCS sets message waiting indication through data coding
+// message waiting group. UCS2 alphabet is preserved if it was already set.
+func (p *SubmitSm) SetMessageWaitingDCS(mw MessageWaiting) {
+	p.DataCoding = MessageWaitingDataCoding(mw, p.DataCoding.Alphabet() == UCS2Alphabet)
+}
+
+// SetMessageWaitingUDH sets message waiting indication through special SMS
+// message indication UDH element. Indication is active if Count is greater
+// than zero, so active indication without count is sent with count of one.
+func (p *SubmitSm) SetMessageWaitingUDH(mw MessageWaiting) error {
+	udh, content, err := p.UDH()
+	if err != nil {
+		return err
+	}
+	udh.AddSpecialSMS(specialSMS(mw))
+	return p.SetUDH(udh, content)
+}
+
+// SetMessageWaitingTLV sets message waiting indication through
+// ms_msg_wait_facilities option.
+func (p *SubmitSm) SetMessageWaitingTLV(mw MessageWaiting) {
+	if p.Options == nil {
+		p.Options = NewOptions()
+	}
+	p.Options.SetSingle(TagMsMsgWaitFacilities, msMsgWaitFacilities(mw))
+}
+
+// MessageWaiting returns all message waiting indications found in data coding,
+// UDH and options.
+func (p SubmitSm) MessageWaiting() ([]MessageWaiting, error) {
+	return messageWaiting(p.DataCoding, p.EsmClass, p.ShortMessage, p.Options)
+}
+
+// MessageWaiting returns all message waiting indications found in data coding,
+// UDH and options.
+func (p DeliverSm) MessageWaiting() ([]MessageWaiting, error) {
+	return messageWaiting(p.DataCoding, p.EsmClass, p.ShortMessage, p.Options)
+}
+
+func specialSMS(mw MessageWaiting) SpecialSMSIndication {
+	count := mw.Count
+	if !mw.Active {
+		count = 0
+	} else if count == 0 {
+		count = 1
+	}
+	return SpecialSMSIndication{
+		Type:  mw.Type,
+		Count: count,
+		Store: mw.Store,
+	}
+}
+
+func msMsgWaitFacilities(mw MessageWaiting) int {
+	b := int(mw.Type & 0x03)
+	if mw.Active {
+		b |= 0x80
+	}
+	return b
+}
+
+func messageWaiting(dc DataCoding, ec EsmClass, sm []byte, opts *Options) ([]MessageWaiting, error) {
+	var out []MessageWaiting
+	if mw, ok := dc.MessageWaiting(); ok {
+		out = append(out, mw)
+	}
+	udh, _, err := messageUDH(ec, sm)
+	if err != nil {
+		return out, err
+	}
+	for _, ind := range udh.SpecialSMS() {
+		out = append(out, MessageWaiting{
+			Type:   ind.Type,
+			Active: ind.Count > 0,
+			Store:  ind.Store,
+			Count:  ind.Count,
+		})
+	}
+	if opts == nil {
+		return out, nil
+	}
+	if b, ok := opts.GetSingle(TagMsMsgWaitFacilities); ok {
+		out = append(out, MessageWaiting{
+			Type:   IndicationType(b & 0x03),
+			Active: b&0x80 != 0,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package pdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessageWaiting(t *testing.T) {
+	sm := &SubmitSm{DataCoding: UCS2DataCoding}
+	sm.SetMessageWaitingDCS(MessageWaiting{Type: VoiceMailIndication, Active: true})
+	if sm.DataCoding != 0xE8 {
+		t.Errorf("dcs 0x%02X expected 0xE8", sm.DataCoding)
+	}
+	if err := sm.SetMessageWaitingUDH(MessageWaiting{Type: EmailIndication, Active: true, Store: true, Count: 4}); err != nil {
+		t.Fatal(err)
+	}
+	sm.SetMessageWaitingTLV(MessageWaiting{Type: FaxIndication})
+	b, err := sm.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsm := &DeliverSm{}
+	if err := dsm.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := dsm.MessageWaiting()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MessageWaiting{
+		{Type: VoiceMailIndication, Active: true, Store: true},
+		{Type: EmailIndication, Active: true, Store: true, Count: 4},
+		{Type: FaxIndication},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("MessageWaiting() => %+v\nexpected %+v", got, expected)
+	}
+}
@@ -135,7 +135,7 @@ type DataSm struct {
 	DestinationAddr    string
 	EsmClass           EsmClass
 	RegisteredDelivery RegisteredDelivery
-	DataCoding         int
+	DataCoding         DataCoding
 	Options            *Options
 }
 
@@ -143,29 +143,35 @@ func (p DataSm) CommandID() CommandID {
 	return DataSmID
 }
 
-func (p DataSm) MarshalBinary() ([]byte, error) {
-	out := append(
-		[]byte(p.ServiceType),
-		0,
-		byte(p.SourceAddrTon),
-		byte(p.SourceAddrNpi),
+// Validate implements pdu.Validator interface.
+func (p DataSm) Validate() error {
+	return firstError(
+		checkCString(ServiceTypeFld, p.ServiceType, serviceTypeSize, StatusInvSerTyp),
+		validateAddrs(p.SourceAddrTon, p.SourceAddrNpi, p.SourceAddr, p.DestAddrTon, p.DestAddrNpi, p.DestinationAddr),
+		checkEsmClass(p.EsmClass),
+		checkRegisteredDelivery(p.RegisteredDelivery),
+		checkOptions(DataSmID, p.Options),
 	)
-	out = append(out, append([]byte(p.SourceAddr), 0)...)
+}
+
+func (p DataSm) MarshalBinary() ([]byte, error) {
+	return p.AppendBinary(nil)
+}
+
+func (p DataSm) AppendBinary(dst []byte) ([]byte, error) {
+	out := appendCString(dst, p.ServiceType)
+	out = append(out, byte(p.SourceAddrTon), byte(p.SourceAddrNpi))
+	out = appendCString(out, p.SourceAddr)
 	out = append(out, byte(p.DestAddrTon), byte(p.DestAddrNpi))
-	out = append(out, append([]byte(p.DestinationAddr), 0)...)
+	out = appendCString(out, p.DestinationAddr)
 	out = append(out, p.EsmClass.Byte(), p.RegisteredDelivery.Byte(), byte(p.DataCoding))
 	if p.Options == nil {
 		return out, nil
 	}
-	opts, err := p.Options.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	return append(out, opts...), nil
+	return p.Options.AppendBinary(out)
 }
 
 func (p *DataSm) UnmarshalBinary(body []byte) error {
-	fmt.Println(body)
 	return fmt.Errorf("Command %s is not supported yet", p.CommandID())
 }
 
@@ -179,8 +185,17 @@ func (p DataSmResp) CommandID() CommandID {
 	return DataSmRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p DataSmResp) Validate() error {
+	return validateMessageIDResp(DataSmRespID, p.MessageID, p.Options)
+}
+
 func (p DataSmResp) MarshalBinary() ([]byte, error) {
-	return nil, fmt.Errorf("Command %s is not supported yet", p.CommandID())
+	return p.AppendBinary(nil)
+}
+
+func (p DataSmResp) AppendBinary(dst []byte) ([]byte, error) {
+	return cStringOptsRespAppend(dst, p.MessageID, p.Options)
 }
 
 func (p *DataSmResp) UnmarshalBinary(body []byte) error {
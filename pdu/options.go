@@ -1,67 +1,179 @@
 package pdu
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"strings"
 )
 
-// Options maps all optional values and provides simple API for access.
-// Only comonly used parameters have helpers, others have to be created
-// by the users.
+// Options holds all optional values and provides simple API for access.
+// All tags defined by the specification have helpers, values of known
+// tags are validated when decoded. Fields are kept and encoded in the
+// order they were set or decoded.
 type Options struct {
-	fields map[TagID][]byte
+	fields []field
 }
 
-// NewOptions creates new options map.
+type field struct {
+	tag TagID
+	val []byte
+}
+
+// NewOptions creates new empty options.
 func NewOptions() *Options {
-	return &Options{
-		fields: make(map[TagID][]byte),
-	}
+	return &Options{}
 }
 
-// Set assigns new TLV field.
+// Set assigns new TLV field. If the tag is already present its value is
+// replaced in place and any repeated occurrences are removed.
 func (o *Options) Set(tag TagID, val []byte) *Options {
-	o.fields[tag] = val
+	for i := range o.fields {
+		if o.fields[i].tag == tag {
+			o.fields[i].val = val
+			o.deleteFrom(i+1, tag)
+			return o
+		}
+	}
+	o.fields = append(o.fields, field{tag, val})
+	return o
+}
+
+// Add appends TLV field. Tags that the specification allows to be repeated
+// (see TagDef.Multiple) and unregistered tags are added as another
+// occurrence, others are set.
+func (o *Options) Add(tag TagID, val []byte) *Options {
+	if !repeatable(tag) {
+		return o.Set(tag, val)
+	}
+	o.fields = append(o.fields, field{tag, val})
+	return o
+}
+
+// repeatable reports whether the tag may occur more than once. Nothing is
+// known about unregistered tags so they are not restricted.
+func repeatable(tag TagID) bool {
+	def, ok := LookupTag(tag)
+	return !ok || def.Multiple
+}
+
+// Delete removes all occurrences of the tags.
+func (o *Options) Delete(tags ...TagID) *Options {
+	for _, tag := range tags {
+		o.deleteFrom(0, tag)
+	}
 	return o
 }
 
+func (o *Options) deleteFrom(start int, tag TagID) {
+	n := start
+	for _, f := range o.fields[start:] {
+		if f.tag != tag {
+			o.fields[n] = f
+			n++
+		}
+	}
+	o.fields = o.fields[:n]
+}
+
+// Len returns number of TLV fields including repeated ones.
+func (o *Options) Len() int {
+	return len(o.fields)
+}
+
+// Range calls f for each TLV field in order until f returns false.
+func (o *Options) Range(f func(tag TagID, val []byte) bool) {
+	for _, fld := range o.fields {
+		if !f(fld.tag, fld.val) {
+			return
+		}
+	}
+}
+
+// Clone returns deep copy of the options.
+func (o *Options) Clone() *Options {
+	c := &Options{fields: make([]field, len(o.fields))}
+	for i, f := range o.fields {
+		c.fields[i] = field{f.tag, append([]byte(nil), f.val...)}
+	}
+	return c
+}
+
+// Equal reports whether both options have the same fields in the same order.
+func (o *Options) Equal(other *Options) bool {
+	if o == nil || other == nil {
+		return o.isEmpty() && other.isEmpty()
+	}
+	if len(o.fields) != len(other.fields) {
+		return false
+	}
+	for i, f := range o.fields {
+		if f.tag != other.fields[i].tag || !bytes.Equal(f.val, other.fields[i].val) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Options) isEmpty() bool {
+	return o == nil || len(o.fields) == 0
+}
+
 // SetSingle assigns new TLV field with one byte value.
 func (o *Options) SetSingle(tag TagID, val int) *Options {
-	o.fields[tag] = []byte{byte(val)}
-	return o
+	return o.Set(tag, []byte{byte(val)})
 }
 
 // SetDouble assigns new TLV field with two bytes value.
 func (o *Options) SetDouble(tag TagID, val int) *Options {
 	b := make([]byte, 2)
 	binary.BigEndian.PutUint16(b, uint16(val))
-	o.fields[tag] = b
-	return o
+	return o.Set(tag, b)
+}
+
+// SetQuad assigns new TLV field with four bytes value.
+func (o *Options) SetQuad(tag TagID, val int) *Options {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(val))
+	return o.Set(tag, b)
 }
 
 // SetString assigns new TLV field with string value.
 func (o *Options) SetString(tag TagID, val string) *Options {
-	o.fields[tag] = []byte(val)
-	return o
+	return o.Set(tag, []byte(val))
 }
 
 // SetCString assigns new TLV field with string value.
 func (o *Options) SetCString(tag TagID, val string) *Options {
-	o.fields[tag] = append([]byte(val), 0)
-	return o
+	return o.Set(tag, append([]byte(val), 0))
 }
 
 // Get tries to get byte value out of TLV field if present. If it's not it
-// returns ok as false.
+// returns ok as false. For repeated tags value of the first one is returned.
 func (o *Options) Get(tag TagID) ([]byte, bool) {
-	val, ok := o.fields[tag]
-	return val, ok
+	for _, f := range o.fields {
+		if f.tag == tag {
+			return f.val, true
+		}
+	}
+	return nil, false
+}
+
+// GetAll returns values of all occurrences of the tag.
+func (o *Options) GetAll(tag TagID) [][]byte {
+	var vals [][]byte
+	for _, f := range o.fields {
+		if f.tag == tag {
+			vals = append(vals, f.val)
+		}
+	}
+	return vals
 }
 
 // GetSingle returns tag value as one byte integer.
 func (o *Options) GetSingle(tag TagID) (int, bool) {
-	val, ok := o.fields[tag]
-	if !ok {
+	val, ok := o.Get(tag)
+	if !ok || len(val) < 1 {
 		return 0, false
 	}
 	return int(val[0]), true
@@ -69,16 +181,25 @@ func (o *Options) GetSingle(tag TagID) (int, bool) {
 
 // GetDouble returns tag value as two byte integer.
 func (o *Options) GetDouble(tag TagID) (int, bool) {
-	b, ok := o.fields[tag]
-	if !ok {
+	b, ok := o.Get(tag)
+	if !ok || len(b) < 2 {
 		return 0, false
 	}
 	return int(binary.BigEndian.Uint16(b)), true
 }
 
+// GetQuad returns tag value as four byte integer.
+func (o *Options) GetQuad(tag TagID) (int, bool) {
+	b, ok := o.Get(tag)
+	if !ok || len(b) < 4 {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint32(b)), true
+}
+
 // GetString returns tag value as string.
 func (o *Options) GetString(tag TagID) (string, bool) {
-	b, ok := o.fields[tag]
+	b, ok := o.Get(tag)
 	if !ok {
 		return "", false
 	}
@@ -87,7 +208,7 @@ func (o *Options) GetString(tag TagID) (string, bool) {
 
 // GetCString returns tag value as string.
 func (o *Options) GetCString(tag TagID) (string, bool) {
-	b, ok := o.fields[tag]
+	b, ok := o.Get(tag)
 	if !ok || len(b) == 0 {
 		return "", false
 	}
@@ -166,6 +287,328 @@ func (o *Options) ReceiptedMessageID() string {
 	return val
 }
 
+// DestAddrSubUnit is helper function for getting this option.
+func (o *Options) DestAddrSubUnit() int {
+	val, ok := o.GetSingle(TagDestAddrSubUnit)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DestNetworkType is helper function for getting this option.
+func (o *Options) DestNetworkType() int {
+	val, ok := o.GetSingle(TagDestNetworkType)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DestBearerType is helper function for getting this option.
+func (o *Options) DestBearerType() int {
+	val, ok := o.GetSingle(TagDestBearerType)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SourceAddrSubunit is helper function for getting this option.
+func (o *Options) SourceAddrSubunit() int {
+	val, ok := o.GetSingle(TagSourceAddrSubunit)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SourceNetworkType is helper function for getting this option.
+func (o *Options) SourceNetworkType() int {
+	val, ok := o.GetSingle(TagSourceNetworkType)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SourceBearerType is helper function for getting this option.
+func (o *Options) SourceBearerType() int {
+	val, ok := o.GetSingle(TagSourceBearerType)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// PayloadType is helper function for getting this option.
+func (o *Options) PayloadType() int {
+	val, ok := o.GetSingle(TagPayloadType)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// MsMsgWaitFacilities is helper function for getting this option.
+func (o *Options) MsMsgWaitFacilities() int {
+	val, ok := o.GetSingle(TagMsMsgWaitFacilities)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// PrivacyIndicator is helper function for getting this option.
+func (o *Options) PrivacyIndicator() int {
+	val, ok := o.GetSingle(TagPrivacyIndicator)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// UserResponseCode is helper function for getting this option.
+func (o *Options) UserResponseCode() int {
+	val, ok := o.GetSingle(TagUserResponseCode)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// LanguageIndicator is helper function for getting this option.
+func (o *Options) LanguageIndicator() int {
+	val, ok := o.GetSingle(TagLanguageIndicator)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// CallbackNumPresInd is helper function for getting this option.
+func (o *Options) CallbackNumPresInd() int {
+	val, ok := o.GetSingle(TagCallbackNumPresInd)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// NumberOfMessages is helper function for getting this option.
+func (o *Options) NumberOfMessages() int {
+	val, ok := o.GetSingle(TagNumberOfMessages)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DpfResult is helper function for getting this option.
+func (o *Options) DpfResult() int {
+	val, ok := o.GetSingle(TagDpfResult)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SetDPF is helper function for getting this option.
+func (o *Options) SetDPF() int {
+	val, ok := o.GetSingle(TagSetDPF)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// MsAvailabilityStatus is helper function for getting this option.
+func (o *Options) MsAvailabilityStatus() int {
+	val, ok := o.GetSingle(TagMsAvailabilityStatus)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DeliveryFailureReason is helper function for getting this option.
+func (o *Options) DeliveryFailureReason() int {
+	val, ok := o.GetSingle(TagDeliveryFailureReason)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// MoreMessagesToSend is helper function for getting this option.
+func (o *Options) MoreMessagesToSend() int {
+	val, ok := o.GetSingle(TagMoreMessagesToSend)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// UssdServiceOp is helper function for getting this option.
+func (o *Options) UssdServiceOp() int {
+	val, ok := o.GetSingle(TagUssdServiceOp)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DisplayTime is helper function for getting this option.
+func (o *Options) DisplayTime() int {
+	val, ok := o.GetSingle(TagDisplayTime)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// MsValidity is helper function for getting this option.
+func (o *Options) MsValidity() int {
+	val, ok := o.GetSingle(TagMsValidity)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// ItsReplyType is helper function for getting this option.
+func (o *Options) ItsReplyType() int {
+	val, ok := o.GetSingle(TagItsReplyType)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DestTelematicsID is helper function for getting this option.
+func (o *Options) DestTelematicsID() int {
+	val, ok := o.GetDouble(TagDestTelematicsID)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SourcePort is helper function for getting this option.
+func (o *Options) SourcePort() int {
+	val, ok := o.GetDouble(TagSourcePort)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// DestinationPort is helper function for getting this option.
+func (o *Options) DestinationPort() int {
+	val, ok := o.GetDouble(TagDestinationPort)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SmsSignal is helper function for getting this option.
+func (o *Options) SmsSignal() int {
+	val, ok := o.GetDouble(TagSmsSignal)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SourceSubaddress is helper function for getting this option.
+func (o *Options) SourceSubaddress() []byte {
+	val, ok := o.Get(TagSourceSubaddress)
+	if !ok {
+		return nil
+	}
+	return val
+}
+
+// DestSubaddress is helper function for getting this option.
+func (o *Options) DestSubaddress() []byte {
+	val, ok := o.Get(TagDestSubaddress)
+	if !ok {
+		return nil
+	}
+	return val
+}
+
+// CallbackNum is helper function for getting this option.
+func (o *Options) CallbackNum() []byte {
+	val, ok := o.Get(TagCallbackNum)
+	if !ok {
+		return nil
+	}
+	return val
+}
+
+// CallbackNumA is helper function for getting this option.
+func (o *Options) CallbackNumA() []byte {
+	val, ok := o.Get(TagCallbackNumA)
+	if !ok {
+		return nil
+	}
+	return val
+}
+
+// ItsSessionInfo is helper function for getting this option.
+func (o *Options) ItsSessionInfo() []byte {
+	val, ok := o.Get(TagItsSessionInfo)
+	if !ok {
+		return nil
+	}
+	return val
+}
+
+// QosTimeToLive is helper function for getting this option.
+func (o *Options) QosTimeToLive() int {
+	val, ok := o.GetQuad(TagQosTimeToLive)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+// SourceTelematicsID is helper function for getting this option.
+func (o *Options) SourceTelematicsID() int {
+	val, ok := o.Get(TagSourceTelematicsID)
+	if !ok {
+		return 0
+	}
+	return decodeInt(val)
+}
+
+// AdditionalStatusInfoText is helper function for getting this option.
+func (o *Options) AdditionalStatusInfoText() string {
+	val, ok := o.GetCString(TagAdditionalStatusInfoTe)
+	if !ok {
+		return ""
+	}
+	return val
+}
+
+// NetworkErrorCode is helper function for getting this option. It returns
+// network type (1 ANSI-136, 2 IS-95, 3 GSM ...) and network specific error.
+func (o *Options) NetworkErrorCode() (typ int, code int) {
+	val, ok := o.Get(TagNetworkErrorCode)
+	if !ok || len(val) != 3 {
+		return 0, 0
+	}
+	return int(val[0]), int(binary.BigEndian.Uint16(val[1:]))
+}
+
+// AlertOnMessageDelivery is helper function for getting this option.
+func (o *Options) AlertOnMessageDelivery() bool {
+	_, ok := o.Get(TagAlertOnMessageDeliv)
+	return ok
+}
+
 // SetUserMessageReference is helper function for setting this option.
 func (o *Options) SetUserMessageReference(val int) *Options {
 	return o.SetDouble(TagUserMessageReference, val)
@@ -206,32 +649,284 @@ func (o *Options) SetReceiptedMessageID(val string) *Options {
 	return o.SetCString(TagReceiptedMessageID, val)
 }
 
+// SetDestAddrSubUnit is helper function for setting this option.
+func (o *Options) SetDestAddrSubUnit(val int) *Options {
+	return o.SetSingle(TagDestAddrSubUnit, val)
+}
+
+// SetDestNetworkType is helper function for setting this option.
+func (o *Options) SetDestNetworkType(val int) *Options {
+	return o.SetSingle(TagDestNetworkType, val)
+}
+
+// SetDestBearerType is helper function for setting this option.
+func (o *Options) SetDestBearerType(val int) *Options {
+	return o.SetSingle(TagDestBearerType, val)
+}
+
+// SetSourceAddrSubunit is helper function for setting this option.
+func (o *Options) SetSourceAddrSubunit(val int) *Options {
+	return o.SetSingle(TagSourceAddrSubunit, val)
+}
+
+// SetSourceNetworkType is helper function for setting this option.
+func (o *Options) SetSourceNetworkType(val int) *Options {
+	return o.SetSingle(TagSourceNetworkType, val)
+}
+
+// SetSourceBearerType is helper function for setting this option.
+func (o *Options) SetSourceBearerType(val int) *Options {
+	return o.SetSingle(TagSourceBearerType, val)
+}
+
+// SetPayloadType is helper function for setting this option.
+func (o *Options) SetPayloadType(val int) *Options {
+	return o.SetSingle(TagPayloadType, val)
+}
+
+// SetMsMsgWaitFacilities is helper function for setting this option.
+func (o *Options) SetMsMsgWaitFacilities(val int) *Options {
+	return o.SetSingle(TagMsMsgWaitFacilities, val)
+}
+
+// SetPrivacyIndicator is helper function for setting this option.
+func (o *Options) SetPrivacyIndicator(val int) *Options {
+	return o.SetSingle(TagPrivacyIndicator, val)
+}
+
+// SetUserResponseCode is helper function for setting this option.
+func (o *Options) SetUserResponseCode(val int) *Options {
+	return o.SetSingle(TagUserResponseCode, val)
+}
+
+// SetLanguageIndicator is helper function for setting this option.
+func (o *Options) SetLanguageIndicator(val int) *Options {
+	return o.SetSingle(TagLanguageIndicator, val)
+}
+
+// SetCallbackNumPresInd is helper function for setting this option.
+func (o *Options) SetCallbackNumPresInd(val int) *Options {
+	return o.SetSingle(TagCallbackNumPresInd, val)
+}
+
+// SetNumberOfMessages is helper function for setting this option.
+func (o *Options) SetNumberOfMessages(val int) *Options {
+	return o.SetSingle(TagNumberOfMessages, val)
+}
+
+// SetDpfResult is helper function for setting this option.
+func (o *Options) SetDpfResult(val int) *Options {
+	return o.SetSingle(TagDpfResult, val)
+}
+
+// SetSetDPF is helper function for setting this option.
+func (o *Options) SetSetDPF(val int) *Options {
+	return o.SetSingle(TagSetDPF, val)
+}
+
+// SetMsAvailabilityStatus is helper function for setting this option.
+func (o *Options) SetMsAvailabilityStatus(val int) *Options {
+	return o.SetSingle(TagMsAvailabilityStatus, val)
+}
+
+// SetDeliveryFailureReason is helper function for setting this option.
+func (o *Options) SetDeliveryFailureReason(val int) *Options {
+	return o.SetSingle(TagDeliveryFailureReason, val)
+}
+
+// SetMoreMessagesToSend is helper function for setting this option.
+func (o *Options) SetMoreMessagesToSend(val int) *Options {
+	return o.SetSingle(TagMoreMessagesToSend, val)
+}
+
+// SetUssdServiceOp is helper function for setting this option.
+func (o *Options) SetUssdServiceOp(val int) *Options {
+	return o.SetSingle(TagUssdServiceOp, val)
+}
+
+// SetDisplayTime is helper function for setting this option.
+func (o *Options) SetDisplayTime(val int) *Options {
+	return o.SetSingle(TagDisplayTime, val)
+}
+
+// SetMsValidity is helper function for setting this option.
+func (o *Options) SetMsValidity(val int) *Options {
+	return o.SetSingle(TagMsValidity, val)
+}
+
+// SetItsReplyType is helper function for setting this option.
+func (o *Options) SetItsReplyType(val int) *Options {
+	return o.SetSingle(TagItsReplyType, val)
+}
+
+// SetDestTelematicsID is helper function for setting this option.
+func (o *Options) SetDestTelematicsID(val int) *Options {
+	return o.SetDouble(TagDestTelematicsID, val)
+}
+
+// SetSourcePort is helper function for setting this option.
+func (o *Options) SetSourcePort(val int) *Options {
+	return o.SetDouble(TagSourcePort, val)
+}
+
+// SetDestinationPort is helper function for setting this option.
+func (o *Options) SetDestinationPort(val int) *Options {
+	return o.SetDouble(TagDestinationPort, val)
+}
+
+// SetSmsSignal is helper function for setting this option.
+func (o *Options) SetSmsSignal(val int) *Options {
+	return o.SetDouble(TagSmsSignal, val)
+}
+
+// SetSourceSubaddress is helper function for setting this option.
+func (o *Options) SetSourceSubaddress(val []byte) *Options {
+	return o.Set(TagSourceSubaddress, val)
+}
+
+// SetDestSubaddress is helper function for setting this option.
+func (o *Options) SetDestSubaddress(val []byte) *Options {
+	return o.Set(TagDestSubaddress, val)
+}
+
+// SetCallbackNum is helper function for setting this option.
+func (o *Options) SetCallbackNum(val []byte) *Options {
+	return o.Set(TagCallbackNum, val)
+}
+
+// SetCallbackNumA is helper function for setting this option.
+func (o *Options) SetCallbackNumA(val []byte) *Options {
+	return o.Set(TagCallbackNumA, val)
+}
+
+// SetItsSessionInfo is helper function for setting this option.
+func (o *Options) SetItsSessionInfo(val []byte) *Options {
+	return o.Set(TagItsSessionInfo, val)
+}
+
+// SetQosTimeToLive is helper function for setting this option.
+func (o *Options) SetQosTimeToLive(val int) *Options {
+	return o.SetQuad(TagQosTimeToLive, val)
+}
+
+// SetSourceTelematicsID is helper function for setting this option.
+func (o *Options) SetSourceTelematicsID(val int) *Options {
+	return o.SetSingle(TagSourceTelematicsID, val)
+}
+
+// SetAdditionalStatusInfoText is helper function for setting this option.
+func (o *Options) SetAdditionalStatusInfoText(val string) *Options {
+	return o.SetCString(TagAdditionalStatusInfoTe, val)
+}
+
+// SetNetworkErrorCode is helper function for setting this option.
+func (o *Options) SetNetworkErrorCode(typ int, code int) *Options {
+	b := []byte{byte(typ), 0, 0}
+	binary.BigEndian.PutUint16(b[1:], uint16(code))
+	return o.Set(TagNetworkErrorCode, b)
+}
+
+// SetAlertOnMessageDelivery is helper function for setting this option.
+func (o *Options) SetAlertOnMessageDelivery() *Options {
+	return o.Set(TagAlertOnMessageDeliv, nil)
+}
+
+// Value returns decoded value of the tag using its registered definition.
+// Values of unknown tags are returned as raw bytes. If tag isn't present
+// nil is returned.
+func (o *Options) Value(tag TagID) (interface{}, error) {
+	val, ok := o.Get(tag)
+	if !ok {
+		return nil, nil
+	}
+	def, ok := LookupTag(tag)
+	if !ok {
+		return val, nil
+	}
+	return def.Value(val)
+}
+
+// String returns options formatted for debugging.
+func (o *Options) String() string {
+	var sb strings.Builder
+	for i, f := range o.fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		var val interface{} = f.val
+		if def, ok := LookupTag(f.tag); ok {
+			if v, err := def.Value(f.val); err == nil {
+				val = v
+			}
+		}
+		switch v := val.(type) {
+		case []byte:
+			fmt.Fprintf(&sb, "%s=%X", f.tag, v)
+		case string:
+			fmt.Fprintf(&sb, "%s=%q", f.tag, v)
+		default:
+			fmt.Fprintf(&sb, "%s=%v", f.tag, v)
+		}
+	}
+	return sb.String()
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (o *Options) MarshalBinary() ([]byte, error) {
-	var out []byte
-	for tag, val := range o.fields {
-		tlv := make([]byte, 4+len(val))
-		binary.BigEndian.PutUint16(tlv[:2], uint16(tag))
-		binary.BigEndian.PutUint16(tlv[2:4], uint16(len(val)))
-		copy(tlv[4:], val)
-		out = append(out, tlv...)
+	return o.AppendBinary(nil)
+}
+
+// AppendBinary appends TLVs in insertion order to dst. Values longer than
+// 16 bit length field can hold are rejected.
+func (o *Options) AppendBinary(dst []byte) ([]byte, error) {
+	for _, f := range o.fields {
+		if len(f.val) > 0xFFFF {
+			return dst, ValidationError{
+				Field:  f.tag.String(),
+				Status: StatusInvParLen,
+				Reason: fmt.Sprintf("value length %d exceeds 65535", len(f.val)),
+			}
+		}
+		dst = append(dst, byte(f.tag>>8), byte(f.tag), byte(len(f.val)>>8), byte(len(f.val)))
+		dst = append(dst, f.val...)
 	}
-	return out, nil
+	return dst, nil
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (o *Options) UnmarshalBinary(buf []byte) error {
 	n := 0
 	for n < len(buf) {
-		if len(buf)-n <= 4 {
-			return fmt.Errorf("smpp/pdu: invalid optional body length")
+		if len(buf)-n < 4 {
+			return ValidationError{
+				Field:  "optional parameters",
+				Status: StatusInvOptParStream,
+				Reason: fmt.Sprintf("truncated tlv header of length %d", len(buf)-n),
+			}
 		}
 		tag := TagID(binary.BigEndian.Uint16(buf[n : n+2]))
 		l := int(binary.BigEndian.Uint16(buf[n+2 : n+4]))
-		if n+4+l >= len(buf)+1 {
-			return fmt.Errorf("smpp/pdu: invalid optional field length (%s %d)", tag, l)
+		if n+4+l > len(buf) {
+			return ValidationError{
+				Field:  tag.String(),
+				Status: StatusInvOptParStream,
+				Reason: fmt.Sprintf("length %d exceeds remaining body", l),
+			}
+		}
+		val := buf[n+4 : n+4+l]
+		if def, ok := LookupTag(tag); ok {
+			if err := def.Validate(val); err != nil {
+				return err
+			}
+		}
+		if _, dup := o.Get(tag); dup && !repeatable(tag) {
+			return ValidationError{
+				Field:  tag.String(),
+				Status: StatusInvOptParStream,
+				Reason: "repeated tlv",
+			}
 		}
-		o.fields[tag] = buf[n+4 : n+4+l]
+		o.Add(tag, val)
 		n += 4 + l
 	}
 	return nil
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"sync"
 	"time"
 
 	smpptime "github.com/pentolbakso/smpp-go/time"
@@ -19,6 +21,13 @@ type PDU interface {
 	encoding.BinaryUnmarshaler
 }
 
+// Appender is implemented by PDUs that can append their binary
+// representation to existing slice. Encoder uses it to marshal
+// PDU directly into pooled buffer after the header.
+type Appender interface {
+	AppendBinary(dst []byte) ([]byte, error)
+}
+
 // EsmClass is used to indicate special message attributes associated with the short message.
 type EsmClass struct {
 	Mode    int // Messaging Mode (bits 1-0)
@@ -127,16 +136,19 @@ const (
 	YesInterNotification = 0x1
 )
 
-func writeTime(layout smpptime.Layout, t time.Time) ([]byte, error) {
-	var schedDel []byte
+func appendTime(dst []byte, layout smpptime.Layout, t time.Time) ([]byte, error) {
 	if !t.IsZero() {
 		out, err := smpptime.Format(layout, t)
 		if err != nil {
-			return nil, err
+			return dst, err
 		}
-		schedDel = []byte(out)
+		dst = append(dst, out...)
 	}
-	return append(schedDel, 0), nil
+	return append(dst, 0), nil
+}
+
+func appendCString(dst []byte, s string) []byte {
+	return append(append(dst, s...), 0)
 }
 
 type pduReader struct {
@@ -182,7 +194,7 @@ func (r *pduReader) ReadString(limit int) ([]byte, error) {
 		return nil, err
 	}
 	if n != int(l) {
-		return nil, errors.New("read count missmatch")
+		return nil, io.ErrUnexpectedEOF
 	}
 	return out, nil
 }
@@ -208,16 +220,12 @@ func cStringOptsRespUnmarshal(body []byte) (string, *Options, error) {
 	return string(body[:n-1]), opts, nil
 }
 
-func cStringOptsRespMarshal(str string, opts *Options) ([]byte, error) {
-	out := append([]byte(str), 0)
+func cStringOptsRespAppend(dst []byte, str string, opts *Options) ([]byte, error) {
+	dst = appendCString(dst, str)
 	if opts == nil {
-		return out, nil
-	}
-	o, err := opts.MarshalBinary()
-	if err != nil {
-		return nil, err
+		return dst, nil
 	}
-	return append(out, o...), nil
+	return opts.AppendBinary(dst)
 }
 
 // Sequencer provides way of altering default PDU sequencing.
@@ -263,37 +271,87 @@ func NewEncoder(w io.Writer, seq Sequencer) *Encoder {
 }
 
 type encoderOpts struct {
-	seq    uint32
-	status Status
+	seq      uint32
+	status   Status
+	validate bool
 }
 
 // Encode PDU structure and write it to the assigned writer.
+// PDU is encoded into pooled buffer which is reused after the write.
 func (en *Encoder) Encode(p PDU, opts ...EncoderOption) (uint32, error) {
-	// TODO consider introducing convention where pdu.MarshalBinary
-	// should return slice with prepended space for header to avoid
-	// allocation and copy.
-	body, err := p.MarshalBinary()
+	bp := GetBuffer()
+	defer PutBuffer(bp)
+	buf, seq, err := en.AppendTo((*bp)[:0], p, opts...)
+	*bp = buf
 	if err != nil {
 		return 0, err
 	}
+	_, err = en.w.Write(buf)
+	return seq, err
+}
 
+// AppendTo encodes PDU structure with the header and appends it to buf
+// instead of writing it, so callers owning the buffer avoid the copy.
+// PDUs implementing Appender are marshaled directly into the buffer after
+// the header. Sequence number is taken from sequencer only if the PDU is
+// encoded successfully.
+func (en *Encoder) AppendTo(buf []byte, p PDU, opts ...EncoderOption) ([]byte, uint32, error) {
 	eOpts := encoderOpts{}
 	for _, o := range opts {
 		o(&eOpts)
 	}
-
-	l := len(body) + 16
-	buf := make([]byte, l)
-	binary.BigEndian.PutUint32(buf[:4], uint32(l))
-	binary.BigEndian.PutUint32(buf[4:8], uint32(p.CommandID()))
-	binary.BigEndian.PutUint32(buf[8:12], uint32(eOpts.status))
+	if v, ok := p.(Validator); ok && eOpts.validate {
+		if err := v.Validate(); err != nil {
+			return buf, 0, err
+		}
+	}
+	start := len(buf)
+	buf = append(buf, make([]byte, 16)...)
+	var err error
+	if a, ok := p.(Appender); ok {
+		buf, err = a.AppendBinary(buf)
+	} else {
+		var body []byte
+		body, err = p.MarshalBinary()
+		buf = append(buf, body...)
+	}
+	if err != nil {
+		return buf[:start], 0, err
+	}
 	if eOpts.seq == 0 {
 		eOpts.seq = en.seq.Next()
 	}
-	binary.BigEndian.PutUint32(buf[12:16], eOpts.seq)
-	copy(buf[16:], body)
-	_, err = en.w.Write(buf)
-	return eOpts.seq, err
+	h := buf[start:]
+	binary.BigEndian.PutUint32(h[:4], uint32(len(h)))
+	binary.BigEndian.PutUint32(h[4:8], uint32(p.CommandID()))
+	binary.BigEndian.PutUint32(h[8:12], uint32(eOpts.status))
+	binary.BigEndian.PutUint32(h[12:16], eOpts.seq)
+	return buf, eOpts.seq, nil
+}
+
+// Buffers over this capacity are not returned to the pool so that
+// occasional large PDU doesn't keep memory allocated.
+const maxPooledBuffer = 4096
+
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// GetBuffer returns pooled buffer for Encoder.AppendTo. It should be
+// returned with PutBuffer once the encoded PDU is no longer used.
+func GetBuffer() *[]byte {
+	return bufPool.Get().(*[]byte)
+}
+
+// PutBuffer returns buffer to the pool.
+func PutBuffer(bp *[]byte) {
+	if cap(*bp) > maxPooledBuffer {
+		return
+	}
+	bufPool.Put(bp)
 }
 
 type EncoderOption func(*encoderOpts)
@@ -310,19 +368,56 @@ func EncodeStatus(status Status) EncoderOption {
 	}
 }
 
+// EncodeValidate makes encoder validate PDU before encoding it. Invalid
+// PDUs are not written and ValidationError is returned instead.
+func EncodeValidate() EncoderOption {
+	return func(eOpts *encoderOpts) {
+		eOpts.validate = true
+	}
+}
+
+// MaxDiscardLength is the longest PDU whose body Decoder reads out to stay
+// in sync with the peer. Oversize PDUs up to this length are rejected with
+// BodyError and the session continues. Longer command_length is more likely
+// lost framing than a real PDU and reading it out could take up to 4 GB, so
+// Decoder returns LengthError without reading the body and the session is
+// closed after rejecting the PDU.
+const MaxDiscardLength = 64 * 1024
+
 // Decoder reads input from reader and marshals it into PDU.
 type Decoder struct {
-	r io.Reader
+	r      io.Reader
+	maxLen uint32
+}
+
+// DecoderOption configures decoder.
+type DecoderOption func(*Decoder)
+
+// DecodeMaxLength limits command_length of decoded PDUs. Bodies of longer
+// PDUs up to MaxDiscardLength are discarded without being allocated, longer
+// ones are treated as lost framing. Default is MaxPDUSize.
+func DecodeMaxLength(n uint32) DecoderOption {
+	return func(d *Decoder) {
+		d.maxLen = n
+	}
 }
 
 // NewDecoder initializes new PDU decoder.
-func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{
-		r: r,
+func NewDecoder(r io.Reader, opts ...DecoderOption) *Decoder {
+	d := &Decoder{
+		r:      r,
+		maxLen: MaxPDUSize,
 	}
+	for _, o := range opts {
+		o(d)
+	}
+	return d
 }
 
-// Decode reads data from reader and populates PDU.
+// Decode reads data from reader and populates PDU. If PDU is read but its
+// body can't be decoded header and PDU are returned together with BodyError.
+// PDUs over the maximum length and PDUs with unknown command id are read
+// out and returned as BodyError with nil PDU.
 func (d *Decoder) Decode() (Header, PDU, error) {
 	// Read header first.
 	var headerBytes [16]byte
@@ -333,86 +428,136 @@ func (d *Decoder) Decode() (Header, PDU, error) {
 	if err := header.UnmarshalBinary(headerBytes[:]); err != nil {
 		return header, nil, err
 	}
-	if header.length < 16 {
-		return header, nil, fmt.Errorf("smpp: invalid pdu header byte length: %d", header.length)
+	if header.length > d.maxLen {
+		if header.length > MaxDiscardLength {
+			return header, nil, LengthError{Length: header.length}
+		}
+		if err := d.discard(header); err != nil {
+			return header, nil, err
+		}
+		return header, nil, BodyError{
+			Err: ValidationError{
+				Field:  "command_length",
+				Status: StatusInvCmdLen,
+				Reason: fmt.Sprintf("length %d over limit %d", header.length, d.maxLen),
+			},
+			Status: StatusInvCmdLen,
+		}
+	}
+	pdu, ok := newPDU(header.commandID)
+	if !ok {
+		if err := d.discard(header); err != nil {
+			return header, nil, err
+		}
+		return header, nil, BodyError{
+			Err:    fmt.Errorf("smpp/pdu: unknown command id 0x%08X", uint32(header.commandID)),
+			Status: StatusInvCmdID,
+		}
 	}
-	pdu := NewPDU(header.commandID)
 	if header.length == 16 {
 		// not expecting body to read - we're done.
 		return header, pdu, nil
 	}
 	bodyBytes := make([]byte, header.length-16)
-	if len(bodyBytes) > 0 {
-		if _, err := io.ReadFull(d.r, bodyBytes); err != nil {
-			return header, pdu, fmt.Errorf("smpp: pdu length doesn't match read body length %d != %d", header.length, len(bodyBytes))
-		}
+	if _, err := io.ReadFull(d.r, bodyBytes); err != nil {
+		return header, pdu, fmt.Errorf("smpp: pdu length doesn't match read body length %d != %d", header.length, len(bodyBytes))
 	}
 	// Unmarshal binary
 	if err := pdu.UnmarshalBinary(bodyBytes); err != nil {
-		return header, pdu, err
+		status, ok := ErrorStatus(err)
+		if !ok {
+			status = StatusSysErr
+		}
+		return header, pdu, BodyError{Err: err, Status: status}
 	}
 	return header, pdu, nil
 }
 
+// discard reads out body of the PDU so the next one can be decoded.
+func (d *Decoder) discard(h *header) error {
+	n := int64(h.length) - 16
+	if _, err := io.CopyN(ioutil.Discard, d.r, n); err != nil {
+		return fmt.Errorf("smpp: discarding pdu body of length %d: %s", n, err)
+	}
+	return nil
+}
+
 // NewPDU creates new PDU from CommandID.
 func NewPDU(commandID CommandID) PDU {
+	p, ok := newPDU(commandID)
+	if !ok {
+		panic("pdu: unsupported PDU command")
+	}
+	return p
+}
+
+// NewResponse creates empty response PDU for the request command id. If the
+// command has no response ok is false.
+func NewResponse(id CommandID) (PDU, bool) {
+	if !IsRequest(id) {
+		return nil, false
+	}
+	return newPDU(id | 0x80000000)
+}
+
+func newPDU(commandID CommandID) (PDU, bool) {
 	switch commandID {
 	case GenericNackID:
-		return &GenericNack{}
+		return &GenericNack{}, true
 	case BindReceiverID:
-		return &BindRx{}
+		return &BindRx{}, true
 	case BindReceiverRespID:
-		return &BindRxResp{}
+		return &BindRxResp{}, true
 	case BindTransmitterID:
-		return &BindTx{}
+		return &BindTx{}, true
 	case BindTransmitterRespID:
-		return &BindTxResp{}
+		return &BindTxResp{}, true
 	case BindTransceiverID:
-		return &BindTRx{}
+		return &BindTRx{}, true
 	case BindTransceiverRespID:
-		return &BindTRxResp{}
+		return &BindTRxResp{}, true
 	case EnquireLinkID:
-		return &EnquireLink{}
+		return &EnquireLink{}, true
 	case EnquireLinkRespID:
-		return &EnquireLinkResp{}
+		return &EnquireLinkResp{}, true
 	case QuerySmID:
-		return &QuerySm{}
+		return &QuerySm{}, true
 	case QuerySmRespID:
-		return &QuerySmResp{}
+		return &QuerySmResp{}, true
 	case SubmitSmID:
-		return &SubmitSm{}
+		return &SubmitSm{}, true
 	case SubmitSmRespID:
-		return &SubmitSmResp{}
+		return &SubmitSmResp{}, true
 	case DeliverSmID:
-		return &DeliverSm{}
+		return &DeliverSm{}, true
 	case DeliverSmRespID:
-		return &DeliverSmResp{}
+		return &DeliverSmResp{}, true
 	case UnbindID:
-		return &Unbind{}
+		return &Unbind{}, true
 	case UnbindRespID:
-		return &UnbindResp{}
+		return &UnbindResp{}, true
 	case ReplaceSmID:
-		return &ReplaceSm{}
+		return &ReplaceSm{}, true
 	case ReplaceSmRespID:
-		return &ReplaceSmResp{}
+		return &ReplaceSmResp{}, true
 	case CancelSmID:
-		return &CancelSm{}
+		return &CancelSm{}, true
 	case CancelSmRespID:
-		return &CancelSmResp{}
+		return &CancelSmResp{}, true
 	case OutbindID:
-		return &Outbind{}
+		return &Outbind{}, true
 	case SubmitMultiID:
-		return &SubmitMulti{}
+		return &SubmitMulti{}, true
 	case SubmitMultiRespID:
-		return &SubmitMultiResp{}
+		return &SubmitMultiResp{}, true
 	case AlertNotificationID:
-		return &AlertNotification{}
+		return &AlertNotification{}, true
 	case DataSmID:
-		return &DataSm{}
+		return &DataSm{}, true
 	case DataSmRespID:
-		return &DataSmResp{}
+		return &DataSmResp{}, true
 	}
-	panic("pdu: unsupported PDU command")
+	return nil, false
 }
 
 // IsRequest returns true if command is request.
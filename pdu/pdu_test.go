@@ -2,7 +2,11 @@ package pdu
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
 	"net"
 	"reflect"
 	"strings"
@@ -168,6 +172,129 @@ func BenchmarkSubmitSm_UnmarshalBinary(b *testing.B) {
 	}
 }
 
+func BenchmarkSubmitSm_AppendBinary(b *testing.B) {
+	p := pduTT[1].pdu.(*SubmitSm)
+	buf := make([]byte, 0, 512)
+	b.SetBytes(285)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bin, err := p.AppendBinary(buf[:0])
+		if err != nil {
+			b.Fatalf("error with marshaling %v", err)
+		}
+		_ = bin
+	}
+}
+
+// marshalEncode is the encoding path used before Appender was introduced.
+func marshalEncode(w io.Writer, p PDU, seq uint32) error {
+	body, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, len(body)+16)
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(buf)))
+	binary.BigEndian.PutUint32(buf[4:8], uint32(p.CommandID()))
+	binary.BigEndian.PutUint32(buf[12:16], seq)
+	copy(buf[16:], body)
+	_, err = w.Write(buf)
+	return err
+}
+
+func BenchmarkEncode_MarshalBinary(b *testing.B) {
+	p := pduTT[1].pdu
+	b.SetBytes(301)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := marshalEncode(ioutil.Discard, p, uint32(i+1)); err != nil {
+			b.Fatalf("error with encoding %v", err)
+		}
+	}
+}
+
+func BenchmarkEncode_AppendBinary(b *testing.B) {
+	p := pduTT[1].pdu
+	enc := NewEncoder(ioutil.Discard, nil)
+	b.SetBytes(301)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := enc.Encode(p); err != nil {
+			b.Fatalf("error with encoding %v", err)
+		}
+	}
+}
+
+func TestAppendBinary(t *testing.T) {
+	prefix := []byte{0xAA, 0xBB}
+	for _, row := range pduTT {
+		a, ok := row.pdu.(Appender)
+		if !ok || row.err {
+			continue
+		}
+		b, err := a.AppendBinary(prefix[:2:2])
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", row.desc, err)
+		}
+		expected := "aabb" + toHexStr(row.hexStr)
+		if hex.EncodeToString(b) != expected {
+			t.Errorf("%s: AppendBinary() => %x\nExpected: %s", row.desc, b, expected)
+		}
+	}
+}
+
+func TestEncodeAllocs(t *testing.T) {
+	var p PDU = &SubmitSm{
+		SourceAddr:      "sender",
+		DestinationAddr: "381641234567",
+		ShortMessage:    []byte("Hello world"),
+		Options:         NewOptions().SetUserMessageReference(1),
+	}
+	enc := NewEncoder(ioutil.Discard, nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := enc.Encode(p, EncodeStatus(StatusOK)); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("encoding submit_sm allocates %.0f times", allocs)
+	}
+}
+
+func TestEncoderAppendTo(t *testing.T) {
+	var p PDU = &SubmitSm{
+		SourceAddr:      "sender",
+		DestinationAddr: "381641234567",
+		ShortMessage:    []byte("Hello world"),
+	}
+	var out bytes.Buffer
+	if _, err := NewEncoder(&out, nil).Encode(p, EncodeSeq(7)); err != nil {
+		t.Fatal(err)
+	}
+	enc := NewEncoder(nil, nil)
+	buf, seq, err := enc.AppendTo([]byte{0xAA}, p, EncodeSeq(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 7 || !bytes.Equal(buf[1:], out.Bytes()) || buf[0] != 0xAA {
+		t.Errorf("AppendTo() => %d %X\nExpected: 7 AA%X", seq, buf, out.Bytes())
+	}
+	buf = make([]byte, 0, 512)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, err := enc.AppendTo(buf[:0], p); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("appending submit_sm allocates %.0f times", allocs)
+	}
+	if _, _, err := enc.AppendTo(nil, &SubmitSm{PriorityFlag: 9}, EncodeValidate()); err == nil {
+		t.Error("expected validation error")
+	}
+}
+
 func TestSeparateUDH(t *testing.T) {
 	udhtest, _ := hex.DecodeString("0B0504158200000003AA0301")
 	b, _ := hex.DecodeString("0B0504158200000003AA030174657374")
@@ -355,3 +482,147 @@ func TestPDUDecodingIncompleteBuffers(t *testing.T) {
 		})
 	}
 }
+
+func TestValidate(t *testing.T) {
+	for _, row := range pduTT {
+		if err := row.pdu.(Validator).Validate(); err != nil {
+			t.Errorf("%s: unexpected error %v", row.desc, err)
+		}
+	}
+	tt := []struct {
+		desc   string
+		pdu    Validator
+		status Status
+	}{
+		{"long source_addr", &SubmitSm{SourceAddr: strings.Repeat("1", 21)}, StatusInvSrcAdr},
+		{"invalid dest_addr_ton", &SubmitSm{DestAddrTon: 7}, StatusInvDstTON},
+		{"invalid source_addr_npi", &DeliverSm{SourceAddrNpi: 2}, StatusInvSrcNPI},
+		{"priority over 3", &SubmitSm{PriorityFlag: 4}, StatusInvPrtFlg},
+		{"long short_message", &SubmitSm{ShortMessage: make([]byte, 255)}, StatusInvMsgLen},
+		{"short_message and payload", &SubmitSm{
+			ShortMessage: []byte("msg"),
+			Options:      NewOptions().SetMessagePayload("payload"),
+		}, StatusInvMsgLen},
+		{"invalid esm_class", &SubmitSm{EsmClass: EsmClass{Mode: NotApplicableEsmMode}}, StatusInvEsmClass},
+		{"invalid registered_delivery", &SubmitSm{RegisteredDelivery: RegisteredDelivery{Receipt: 3}}, StatusInvRegDlvFlg},
+		{"invalid replace_if_present", &DeliverSm{ReplaceIfPresentFlag: 2}, StatusInvRepFlag},
+		{"long system_id", &BindTRx{SystemID: strings.Repeat("a", 16)}, StatusInvSysID},
+		{"long password", &BindTx{Password: "123456789"}, StatusInvPaswd},
+		{"long message_id", &SubmitSmResp{MessageID: strings.Repeat("a", 65)}, StatusInvMsgID},
+		{"option not allowed", &SubmitSm{Options: NewOptions().SetMessageState(1)}, StatusOptParNotAllwd},
+	}
+	for _, row := range tt {
+		err := row.pdu.Validate()
+		if status, ok := ErrorStatus(err); !ok || status != row.status {
+			t.Errorf("%s: Validate() => %v expected status %s", row.desc, err, row.status)
+		}
+	}
+}
+
+func TestEncodeValidate(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf, nil)
+	sm := &SubmitSm{SourceAddr: strings.Repeat("1", 30)}
+	if _, err := enc.Encode(sm, EncodeValidate()); err == nil {
+		t.Errorf("expected validation error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("invalid pdu written %X", buf.Bytes())
+	}
+	if _, err := enc.Encode(sm); err != nil {
+		t.Errorf("unexpected error without validation %v", err)
+	}
+	sm = &SubmitSm{ShortMessage: make([]byte, 300)}
+	if _, err := sm.MarshalBinary(); err == nil {
+		t.Errorf("expected error marshaling long short_message")
+	}
+}
+
+func TestDecodeBodyError(t *testing.T) {
+	tt := []struct {
+		desc   string
+		hexStr string
+		status Status
+	}{
+		{"long source_addr", "00000035|00000004|00000000|00000001|00|00|00|313233343536373839303132333435363738393031323300|00|00|00|00|00|00|00|00|00|00|00|00|00|00", StatusInvSrcAdr},
+		{"truncated body", "00000013|00000004|00000000|00000001|000000", StatusInvCmdLen},
+		{"short_message over body", "00000021|00000004|00000000|00000001|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|05", StatusInvCmdLen},
+		{"invalid tlv stream", "00000024|00000004|00000000|00000001|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|020A00", StatusInvOptParStream},
+		{"invalid tlv length", "00000026|00000004|00000000|00000001|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|00|020A000100", StatusInvParLen},
+	}
+	for _, row := range tt {
+		b, _ := hex.DecodeString(toHexStr(row.hexStr))
+		h, p, err := NewDecoder(bytes.NewReader(b)).Decode()
+		var berr BodyError
+		if !errors.As(err, &berr) || berr.Status != row.status {
+			t.Errorf("%s: Decode() => %v expected body error with status %s", row.desc, err, row.status)
+			continue
+		}
+		if h == nil || p == nil || h.Sequence() != 1 {
+			t.Errorf("%s: expected header and pdu with body error", row.desc)
+		}
+	}
+}
+
+func TestDecodeDiscard(t *testing.T) {
+	var b []byte
+	oversize, _ := hex.DecodeString(toHexStr("00000020|00000004|00000000|00000001|00000000000000000000000000000000"))
+	unknown, _ := hex.DecodeString(toHexStr("00000014|00000999|00000000|00000002|01020304"))
+	enquire, _ := hex.DecodeString(toHexStr("00000010|00000015|00000000|00000003"))
+	b = append(b, oversize...)
+	b = append(b, unknown...)
+	b = append(b, enquire...)
+	dec := NewDecoder(bytes.NewReader(b), DecodeMaxLength(24))
+	for _, status := range []Status{StatusInvCmdLen, StatusInvCmdID} {
+		h, p, err := dec.Decode()
+		var berr BodyError
+		if !errors.As(err, &berr) || berr.Status != status || p != nil {
+			t.Fatalf("Decode() => %v %v expected body error with status %s", p, err, status)
+		}
+		if h == nil {
+			t.Fatalf("expected header with body error")
+		}
+	}
+	h, p, err := dec.Decode()
+	if err != nil || h.Sequence() != 3 || p.CommandID() != EnquireLinkID {
+		t.Errorf("Decode() => %v %v after discarded pdus", p, err)
+	}
+}
+
+func TestDecodeOverDiscardLength(t *testing.T) {
+	b, _ := hex.DecodeString(toHexStr("FFFFFFFF|00000004|00000000|00000001"))
+	r := bytes.NewReader(append(b, make([]byte, 1024)...))
+	h, p, err := NewDecoder(r).Decode()
+	var lerr LengthError
+	if !errors.As(err, &lerr) || p != nil || h.Sequence() != 1 {
+		t.Fatalf("Decode() => %v %v expected length error", p, err)
+	}
+	if s := err.Error(); !strings.Contains(s, "over limit 65536") {
+		t.Errorf("length error %s", s)
+	}
+	if r.Len() != 1024 {
+		t.Errorf("body of %d bytes read after framing error", 1024-r.Len())
+	}
+}
+
+func TestNotSupportedNotValidated(t *testing.T) {
+	for _, p := range []PDU{&ReplaceSm{}, &CancelSm{}, &SubmitMulti{}, &SubmitMultiResp{}, &AlertNotification{}, &Outbind{}} {
+		if _, ok := p.(Validator); ok {
+			t.Errorf("%s without fields claims to be validated", p.CommandID())
+		}
+	}
+}
+
+func TestMinimalMessageBody(t *testing.T) {
+	body := make([]byte, minMessageBodyLen)
+	if err := new(DeliverSm).UnmarshalBinary(body); err != nil {
+		t.Errorf("minimal deliver_sm %v", err)
+	}
+	if err := new(SubmitSm).UnmarshalBinary(body); err != nil {
+		t.Errorf("minimal submit_sm %v", err)
+	}
+	err := new(SubmitSm).UnmarshalBinary(body[:minMessageBodyLen-1])
+	if status, ok := ErrorStatus(err); !ok || status != StatusInvCmdLen {
+		t.Errorf("truncated submit_sm %v", err)
+	}
+}
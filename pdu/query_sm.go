@@ -1,7 +1,6 @@
 package pdu
 
 import (
-	"fmt"
 	"time"
 
 	smpptime "github.com/pentolbakso/smpp-go/time"
@@ -20,6 +19,16 @@ func (p QuerySm) CommandID() CommandID {
 	return QuerySmID
 }
 
+// Validate implements pdu.Validator interface.
+func (p QuerySm) Validate() error {
+	return firstError(
+		checkCString(MessageIDFld, p.MessageID, messageIDSize, StatusInvMsgID),
+		checkTon(SourceAddrTonFld, p.SourceAddrTon, StatusInvSrcTON),
+		checkNpi(SourceAddrNpiFld, p.SourceAddrNpi, StatusInvSrcNPI),
+		checkCString(SourceAddrFld, p.SourceAddr, addrSize, StatusInvSrcAdr),
+	)
+}
+
 // Response creates new QuerySmResp.
 func (p QuerySm) Response(date time.Time, state, err int) *QuerySmResp {
 	return &QuerySmResp{
@@ -32,36 +41,40 @@ func (p QuerySm) Response(date time.Time, state, err int) *QuerySmResp {
 
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p QuerySm) MarshalBinary() ([]byte, error) {
-	out := append([]byte(p.MessageID), 0)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p QuerySm) AppendBinary(dst []byte) ([]byte, error) {
+	out := appendCString(dst, p.MessageID)
 	out = append(out, byte(p.SourceAddrTon), byte(p.SourceAddrNpi))
-	out = append(out, append([]byte(p.SourceAddr), 0)...)
-	return out, nil
+	return appendCString(out, p.SourceAddr), nil
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p *QuerySm) UnmarshalBinary(body []byte) error {
-	if len(body) < 6 {
-		return fmt.Errorf("smpp/pdu: query_sm body too short: %d", len(body))
+	if len(body) < 4 {
+		return bodyTooShort("query_sm", len(body))
 	}
 	buf := newBuffer(body)
 	res, err := buf.ReadCString(65)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding message_id %s", err)
+		return decodeError(MessageIDFld, err)
 	}
 	p.MessageID = string(res)
 	b, err := buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding source_addr_ton %s", err)
+		return decodeError(SourceAddrTonFld, err)
 	}
 	p.SourceAddrTon = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding source_addr_npi %s", err)
+		return decodeError(SourceAddrNpiFld, err)
 	}
 	p.SourceAddrNpi = int(b)
 	res, err = buf.ReadCString(21)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding source_addr %s", err)
+		return decodeError(SourceAddrFld, err)
 	}
 	p.SourceAddr = string(res)
 	return nil
@@ -80,46 +93,53 @@ func (p QuerySmResp) CommandID() CommandID {
 	return QuerySmRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p QuerySmResp) Validate() error {
+	return checkCString(MessageIDFld, p.MessageID, messageIDSize, StatusInvMsgID)
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p QuerySmResp) MarshalBinary() ([]byte, error) {
-	out := append([]byte(p.MessageID), 0)
-	tm, err := writeTime(smpptime.Absolute, p.FinalDate)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p QuerySmResp) AppendBinary(dst []byte) ([]byte, error) {
+	out, err := appendTime(appendCString(dst, p.MessageID), smpptime.Absolute, p.FinalDate)
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
-	out = append(out, tm...)
-	out = append(out, byte(p.MessageState), byte(p.ErrorCode))
-	return out, nil
+	return append(out, byte(p.MessageState), byte(p.ErrorCode)), nil
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p *QuerySmResp) UnmarshalBinary(body []byte) error {
-	if len(body) < 6 {
-		return fmt.Errorf("smpp/pdu: query_sm body too short: %d", len(body))
+	if len(body) < 4 {
+		return bodyTooShort("query_sm", len(body))
 	}
 	buf := newBuffer(body)
 	res, err := buf.ReadCString(65)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding message_id %s", err)
+		return decodeError(MessageIDFld, err)
 	}
 	p.MessageID = string(res)
 	res, err = buf.ReadCString(17)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding final_date %s", err)
+		return decodeError(FinalDateFld, err)
 	}
 	t, err := smpptime.Parse(res)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding final_date %s", err)
+		return decodeError(FinalDateFld, err)
 	}
 	p.FinalDate = t
 	b, err := buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding message_state %s", err)
+		return decodeError(MessageStateFld, err)
 	}
 	p.MessageState = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding error_code %s", err)
+		return decodeError(ErrorCodeFld, err)
 	}
 	p.ErrorCode = int(b)
 	return nil
@@ -60,11 +60,20 @@ var DelStatMap = map[uint8]DeliveryStat{
 	8: DelStatRejected,
 }
 
+// delStatStates maps receipt states back to message_state values.
+var delStatStates = func() map[DeliveryStat]uint8 {
+	m := make(map[DeliveryStat]uint8, len(DelStatMap))
+	for state, stat := range DelStatMap {
+		m[stat] = state
+	}
+	return m
+}()
+
+// receiptTextLen is number of characters of the message text in receipt.
+const receiptTextLen = 20
+
 func (dr *DeliveryReceipt) String() string {
-	return fmt.Sprintf(
-		"id:%s sub:%d dlvrd:%d submit date:%s done date:%s stat:%s err:%d text:%s",
-		dr.Id, dr.Sub, dr.Dlvrd, dr.SubmitDate.Format(recDateLayout), dr.DoneDate.Format(recDateLayout), dr.Stat, dr.Err, dr.Text,
-	)
+	return DefaultReceiptFormat.Format(dr)
 }
 
 var (
@@ -72,68 +81,202 @@ var (
 	secRecDateLayout = "060102150405"
 )
 
+// ReceiptFormat describes vendor variant of the receipt in short_message.
+type ReceiptFormat struct {
+	// DateLayout used for formatting dates. Parsing accepts dates with and
+	// without seconds regardless of the layout. Default is YYMMDDhhmm.
+	DateLayout string
+	// PadCounts formats sub, dlvrd and err fields padded to 3 digits.
+	PadCounts bool
+	// CaseInsensitive keys and stat values when parsing.
+	CaseInsensitive bool
+	// AllowMissing fields except id and stat when parsing.
+	AllowMissing bool
+	// IgnoreUnknown fields when parsing instead of returning error.
+	IgnoreUnknown bool
+}
+
+// DefaultReceiptFormat is the format shown in smpp 3.4 specification.
+var DefaultReceiptFormat = ReceiptFormat{DateLayout: recDateLayout}
+
+// Format returns receipt text in the format.
+func (f ReceiptFormat) Format(dr *DeliveryReceipt) string {
+	layout := f.DateLayout
+	if layout == "" {
+		layout = recDateLayout
+	}
+	count := "%d"
+	if f.PadCounts {
+		count = "%03d"
+	}
+	return fmt.Sprintf(
+		"id:%s sub:"+count+" dlvrd:"+count+" submit date:%s done date:%s stat:%s err:"+count+" text:%s",
+		dr.Id, dr.Sub, dr.Dlvrd, dr.SubmitDate.Format(layout), dr.DoneDate.Format(layout), dr.Stat, dr.Err, dr.Text,
+	)
+}
+
 // ParseDeliveryReceipt parses delivery receipt format defined in smpp 3.4 specification
 func ParseDeliveryReceipt(sm string) (*DeliveryReceipt, error) {
+	return DefaultReceiptFormat.Parse(sm)
+}
+
+// index returns position of the key in the text, ignoring case of ASCII
+// letters if the format is case insensitive. Text is searched as is so the
+// position is valid even if it contains letters changing length with case.
+func (f ReceiptFormat) index(s, key string) int {
+	if !f.CaseInsensitive {
+		return strings.Index(s, key)
+	}
+	for i := 0; i+len(key) <= len(s); i++ {
+		if equalFoldASCII(s[i:i+len(key)], key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// equalFoldASCII reports whether strings of the same length are equal
+// ignoring case of ASCII letters.
+func equalFoldASCII(s, t string) bool {
+	for i := 0; i < len(s); i++ {
+		a, b := s[i], t[i]
+		if 'A' <= a && a <= 'Z' {
+			a += 'a' - 'A'
+		}
+		if 'A' <= b && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		if a != b {
+			return false
+		}
+	}
+	return true
+}
+
+func (f ReceiptFormat) parseDate(val string) (time.Time, error) {
+	if f.DateLayout != "" && f.DateLayout != recDateLayout && f.DateLayout != secRecDateLayout {
+		if date, err := time.Parse(f.DateLayout, val); err == nil {
+			return date, nil
+		}
+	}
+	date, err := time.Parse(recDateLayout, val)
+	if err != nil {
+		date, err = time.Parse(secRecDateLayout, val)
+	}
+	return date, err
+}
+
+// Parse parses receipt text in the format.
+func (f ReceiptFormat) Parse(sm string) (*DeliveryReceipt, error) {
 	var receipt DeliveryReceipt
-	textI := strings.Index(sm, " text:")
-	if textI == -1 {
+	formatSm := sm
+	if textI := f.index(sm, " text:"); textI != -1 {
+		receipt.Text = sm[textI+6:]
+		formatSm = sm[:textI]
+	} else if !f.AllowMissing {
 		return &DeliveryReceipt{}, errors.New("smpp: invalid receipt txt ")
 	}
-	textMsg := sm[textI+6:]
-	receipt.Text = textMsg
-	formatSm := sm[:textI]
-	formatSm = strings.Replace(formatSm, "done date:", "done_date:", 1)
-	formatSm = strings.Replace(formatSm, "submit date:", "submit_date:", 1)
-	receiptField := strings.Split(formatSm, " ")
-	if len(receiptField) < 7 {
+	for _, key := range []string{"done date:", "submit date:"} {
+		if i := f.index(formatSm, key); i != -1 {
+			formatSm = formatSm[:i+len(key)-6] + "_" + formatSm[i+len(key)-5:]
+		}
+	}
+	receiptField := strings.Fields(formatSm)
+	if len(receiptField) < 7 && !f.AllowMissing {
 		return &DeliveryReceipt{}, errors.New("smpp: receipt miss key ")
 	}
+	var hasID, hasStat bool
 	for _, fieldWithValue := range receiptField {
 		i := strings.Index(fieldWithValue, ":")
 		if i == -1 {
+			if f.IgnoreUnknown {
+				continue
+			}
 			return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + fieldWithValue)
 		}
-		switch fieldWithValue[:i] {
+		key, val := fieldWithValue[:i], fieldWithValue[i+1:]
+		if f.CaseInsensitive {
+			key = strings.ToLower(key)
+		}
+		switch key {
 		case "id":
-			receipt.Id = fieldWithValue[i+1:]
+			receipt.Id = val
+			hasID = true
 		case "sub", "dlvrd":
-			count, err := strconv.Atoi(fieldWithValue[i+1:])
+			count, err := strconv.Atoi(val)
 			if err != nil {
 				return &DeliveryReceipt{}, err
 			}
-			if fieldWithValue[:i] == "sub" {
+			if key == "sub" {
 				receipt.Sub = count
 			} else {
 				receipt.Dlvrd = count
 			}
-
 		case "submit_date", "done_date":
-
-			date, err := time.Parse(recDateLayout, fieldWithValue[i+1:])
+			date, err := f.parseDate(val)
 			if err != nil {
-				date, err = time.Parse(secRecDateLayout, fieldWithValue[i+1:])
-				if err != nil {
-					return &DeliveryReceipt{}, err
-				}
-
+				return &DeliveryReceipt{}, err
 			}
-			if fieldWithValue[:i] == "submit_date" {
+			if key == "submit_date" {
 				receipt.SubmitDate = date
 			} else {
 				receipt.DoneDate = date
 			}
 		case "stat":
-			receipt.Stat = DeliveryStat(fieldWithValue[i+1:])
+			if f.CaseInsensitive {
+				val = strings.ToUpper(val)
+			}
+			receipt.Stat = DeliveryStat(val)
+			hasStat = true
 		case "err":
-			count, err := strconv.Atoi(fieldWithValue[i+1:])
+			count, err := strconv.Atoi(val)
 			if err != nil {
 				return &DeliveryReceipt{}, err
 			}
 			receipt.Err = DeliveryErr(count)
 		default:
-			return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + fieldWithValue)
+			if !f.IgnoreUnknown {
+				return &DeliveryReceipt{}, errors.New("smpp: invalid receipt format field " + fieldWithValue)
+			}
 		}
-
+	}
+	if f.AllowMissing && (!hasID || !hasStat) {
+		return &DeliveryReceipt{}, errors.New("smpp: receipt miss key ")
 	}
 	return &receipt, nil
 }
+
+// MessageState returns value of message_state TLV for the receipt state.
+func (dr *DeliveryReceipt) MessageState() (int, bool) {
+	state, ok := delStatStates[dr.Stat]
+	return int(state), ok
+}
+
+// DeliverSm builds deliver_sm carrying the receipt of the submitted
+// message. Addresses of the submitted message are swapped as the receipt
+// goes back to its originator. Receipt is set in short_message in the format
+// and in receipted_message_id and message_state TLVs. Text of the receipt is
+// cut to its first 20 characters.
+func (f ReceiptFormat) DeliverSm(dr *DeliveryReceipt, sm *SubmitSm) *DeliverSm {
+	opts := NewOptions().SetReceiptedMessageID(dr.Id)
+	if state, ok := dr.MessageState(); ok {
+		opts.SetMessageState(state)
+	}
+	if text := []rune(dr.Text); len(text) > receiptTextLen {
+		short := *dr
+		short.Text = string(text[:receiptTextLen])
+		dr = &short
+	}
+	return &DeliverSm{
+		ServiceType:     sm.ServiceType,
+		SourceAddrTon:   sm.DestAddrTon,
+		SourceAddrNpi:   sm.DestAddrNpi,
+		SourceAddr:      sm.DestinationAddr,
+		DestAddrTon:     sm.SourceAddrTon,
+		DestAddrNpi:     sm.SourceAddrNpi,
+		DestinationAddr: sm.SourceAddr,
+		EsmClass:        EsmClass{Type: DelRecEsmType},
+		ShortMessage:    []byte(f.Format(dr)),
+		Options:         opts,
+	}
+}
@@ -163,3 +163,105 @@ func BenchmarkParseDeliveryReceipt01(b *testing.B) {
 	}
 	b.StopTimer()
 }
+
+func TestReceiptFormat(t *testing.T) {
+	vendor := "ID:0a1b sub:1 dlvrd:1 Submit Date:161003211236 Done Date:161003211236 stat:delivrd err:0 imsi:123 Text:-"
+	if _, err := ParseDeliveryReceipt(vendor); err == nil {
+		t.Error("default format parsed vendor receipt")
+	}
+	f := ReceiptFormat{CaseInsensitive: true, IgnoreUnknown: true}
+	dr, err := f.Parse(vendor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.Id != "0a1b" || dr.Stat != DelStatDelivered || dr.Text != "-" || dr.DoneDate.Second() != 36 {
+		t.Errorf("unexpected receipt %+v", dr)
+	}
+
+	// Lower case of Ⱥ is longer, positions must be taken from the text itself.
+	f = ReceiptFormat{CaseInsensitive: true, IgnoreUnknown: true, AllowMissing: true}
+	if dr, err := f.Parse("id:ȺȺȺȺȺȺ stat:DELIVRD text:x"); err != nil || dr.Id != "ȺȺȺȺȺȺ" || dr.Text != "x" {
+		t.Errorf("unexpected receipt %+v %v", dr, err)
+	}
+	dr, err = f.Parse("id:ȺȺȺȺȺȺ sub:1 dlvrd:1 submit date:1610032112 Done Date:1610032112 stat:DELIVRD err:0 Text:Ⱥ ȺȺ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.Id != "ȺȺȺȺȺȺ" || dr.Text != "Ⱥ ȺȺ" || dr.DoneDate.Minute() != 12 {
+		t.Errorf("unexpected receipt %+v", dr)
+	}
+
+	short := "id:42 stat:UNDELIV err:5"
+	if _, err := ParseDeliveryReceipt(short); err == nil {
+		t.Error("default format parsed receipt with missing fields")
+	}
+	dr, err = ReceiptFormat{AllowMissing: true}.Parse(short)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.Id != "42" || dr.Stat != DelStatUndeliverable || dr.Err != 5 {
+		t.Errorf("unexpected receipt %+v", dr)
+	}
+	if _, err := (ReceiptFormat{AllowMissing: true}).Parse("sub:1 err:5"); err == nil {
+		t.Error("parsed receipt without id and stat")
+	}
+
+	dr.SubmitDate = time.Date(2016, 10, 3, 21, 12, 36, 0, time.UTC)
+	dr.DoneDate = dr.SubmitDate
+	f = ReceiptFormat{DateLayout: secRecDateLayout, PadCounts: true}
+	want := "id:42 sub:000 dlvrd:000 submit date:161003211236 done date:161003211236 stat:UNDELIV err:005 text:"
+	if s := f.Format(dr); s != want {
+		t.Errorf("Format() => %s expected %s", s, want)
+	}
+	parsed, err := f.Parse(want)
+	if err != nil || !parsed.DoneDate.Equal(dr.DoneDate) || parsed.Err != 5 {
+		t.Errorf("formatted receipt not parsed back %+v %v", parsed, err)
+	}
+}
+
+func TestReceiptDeliverSm(t *testing.T) {
+	sm := &SubmitSm{
+		SourceAddrTon:   5,
+		SourceAddr:      "sender",
+		DestAddrTon:     1,
+		DestAddrNpi:     1,
+		DestinationAddr: "385991234567",
+	}
+	text := "Hello world, this text is too long"
+	dr := &DeliveryReceipt{Id: "abc", Sub: 1, Dlvrd: 1, Stat: DelStatDelivered, Text: text}
+	p := DefaultReceiptFormat.DeliverSm(dr, sm)
+	if dr.Text != text {
+		t.Errorf("receipt text changed %q", dr.Text)
+	}
+	if p.SourceAddr != sm.DestinationAddr || p.SourceAddrTon != 1 || p.DestinationAddr != sm.SourceAddr || p.DestAddrTon != 5 {
+		t.Errorf("addresses not swapped %+v", p)
+	}
+	if p.EsmClass.Type != DelRecEsmType {
+		t.Errorf("esm_class type %d", p.EsmClass.Type)
+	}
+	if id := p.Options.ReceiptedMessageID(); id != "abc" {
+		t.Errorf("receipted_message_id %s", id)
+	}
+	if state := p.Options.MessageState(); state != 2 {
+		t.Errorf("message_state %d", state)
+	}
+	parsed, err := ParseDeliveryReceipt(string(p.ShortMessage))
+	if err != nil || parsed.Id != "abc" || parsed.Stat != DelStatDelivered || parsed.Text != "Hello world, this te" {
+		t.Errorf("short_message receipt not parsed %+v %v", parsed, err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReceiptMessageState(t *testing.T) {
+	for state, stat := range DelStatMap {
+		dr := &DeliveryReceipt{Stat: stat}
+		if s, ok := dr.MessageState(); !ok || s != int(state) {
+			t.Errorf("%s message_state %d %t", stat, s, ok)
+		}
+	}
+	if _, ok := (&DeliveryReceipt{Stat: "OTHER"}).MessageState(); ok {
+		t.Error("message_state for unknown state")
+	}
+}
@@ -8,6 +8,11 @@ func (p Unbind) CommandID() CommandID {
 	return UnbindID
 }
 
+// Validate implements pdu.Validator interface.
+func (p Unbind) Validate() error {
+	return nil
+}
+
 // Response creates new UnbindResp.
 func (p Unbind) Response() *UnbindResp {
 	return &UnbindResp{}
@@ -18,6 +23,11 @@ func (p Unbind) MarshalBinary() ([]byte, error) {
 	return nil, nil
 }
 
+// AppendBinary implements pdu.Appender interface.
+func (p Unbind) AppendBinary(dst []byte) ([]byte, error) {
+	return dst, nil
+}
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p Unbind) UnmarshalBinary(body []byte) error {
 	return nil
@@ -31,11 +41,21 @@ func (p UnbindResp) CommandID() CommandID {
 	return UnbindRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p UnbindResp) Validate() error {
+	return nil
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p UnbindResp) MarshalBinary() ([]byte, error) {
 	return nil, nil
 }
 
+// AppendBinary implements pdu.Appender interface.
+func (p UnbindResp) AppendBinary(dst []byte) ([]byte, error) {
+	return dst, nil
+}
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p UnbindResp) UnmarshalBinary(body []byte) error {
 	return nil
@@ -49,6 +69,11 @@ func (p EnquireLink) CommandID() CommandID {
 	return EnquireLinkID
 }
 
+// Validate implements pdu.Validator interface.
+func (p EnquireLink) Validate() error {
+	return nil
+}
+
 // Response creates new EnquireLinkResp.
 func (p EnquireLink) Response() *EnquireLinkResp {
 	return &EnquireLinkResp{}
@@ -59,6 +84,11 @@ func (p EnquireLink) MarshalBinary() ([]byte, error) {
 	return nil, nil
 }
 
+// AppendBinary implements pdu.Appender interface.
+func (p EnquireLink) AppendBinary(dst []byte) ([]byte, error) {
+	return dst, nil
+}
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p EnquireLink) UnmarshalBinary(body []byte) error {
 	return nil
@@ -72,11 +102,21 @@ func (p EnquireLinkResp) CommandID() CommandID {
 	return EnquireLinkRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p EnquireLinkResp) Validate() error {
+	return nil
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p EnquireLinkResp) MarshalBinary() ([]byte, error) {
 	return nil, nil
 }
 
+// AppendBinary implements pdu.Appender interface.
+func (p EnquireLinkResp) AppendBinary(dst []byte) ([]byte, error) {
+	return dst, nil
+}
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p EnquireLinkResp) UnmarshalBinary(body []byte) error {
 	return nil
@@ -90,11 +130,21 @@ func (p GenericNack) CommandID() CommandID {
 	return GenericNackID
 }
 
+// Validate implements pdu.Validator interface.
+func (p GenericNack) Validate() error {
+	return nil
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p GenericNack) MarshalBinary() ([]byte, error) {
 	return nil, nil
 }
 
+// AppendBinary implements pdu.Appender interface.
+func (p GenericNack) AppendBinary(dst []byte) ([]byte, error) {
+	return dst, nil
+}
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p GenericNack) UnmarshalBinary(body []byte) error {
 	return nil
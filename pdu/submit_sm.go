@@ -1,7 +1,6 @@
 package pdu
 
 import (
-	"fmt"
 	"time"
 
 	smpptime "github.com/pentolbakso/smpp-go/time"
@@ -26,7 +25,7 @@ type SubmitSm struct {
 	ValidityPeriod       time.Time
 	RegisteredDelivery   RegisteredDelivery
 	ReplaceIfPresentFlag int
-	DataCoding           int // DataCoding see more:https://en.wikipedia.org/wiki/Data_Coding_Scheme
+	DataCoding           DataCoding
 	SmDefaultMsgID       int
 	ShortMessage         []byte
 	Options              *Options
@@ -37,6 +36,20 @@ func (p SubmitSm) CommandID() CommandID {
 	return SubmitSmID
 }
 
+// Validate implements pdu.Validator interface.
+func (p SubmitSm) Validate() error {
+	return firstError(
+		checkCString(ServiceTypeFld, p.ServiceType, serviceTypeSize, StatusInvSerTyp),
+		validateAddrs(p.SourceAddrTon, p.SourceAddrNpi, p.SourceAddr, p.DestAddrTon, p.DestAddrNpi, p.DestinationAddr),
+		checkEsmClass(p.EsmClass),
+		checkRange(PriorityFlagFld, p.PriorityFlag, 3, StatusInvPrtFlg),
+		checkRegisteredDelivery(p.RegisteredDelivery),
+		checkRange(ReplaceIfPresentFlagFld, p.ReplaceIfPresentFlag, 1, StatusInvRepFlag),
+		checkShortMessage(p.ShortMessage, p.Options),
+		checkOptions(SubmitSmID, p.Options),
+	)
+}
+
 // Response creates new SubmitSmResp.
 func (p SubmitSm) Response(msgID string) *SubmitSmResp {
 	return &SubmitSmResp{
@@ -46,138 +59,140 @@ func (p SubmitSm) Response(msgID string) *SubmitSmResp {
 
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p SubmitSm) MarshalBinary() ([]byte, error) {
-	out := append(
-		[]byte(p.ServiceType),
-		0,
-		byte(p.SourceAddrTon),
-		byte(p.SourceAddrNpi),
-	)
-	out = append(out, append([]byte(p.SourceAddr), 0)...)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p SubmitSm) AppendBinary(dst []byte) ([]byte, error) {
+	l := len(p.ShortMessage)
+	if l > maxShortMessageLen {
+		return dst, checkShortMessage(p.ShortMessage, nil)
+	}
+	out := appendCString(dst, p.ServiceType)
+	out = append(out, byte(p.SourceAddrTon), byte(p.SourceAddrNpi))
+	out = appendCString(out, p.SourceAddr)
 	out = append(out, byte(p.DestAddrTon), byte(p.DestAddrNpi))
-	out = append(out, append([]byte(p.DestinationAddr), 0)...)
+	out = appendCString(out, p.DestinationAddr)
 	out = append(out, p.EsmClass.Byte(), byte(p.ProtocolID), byte(p.PriorityFlag))
-	tm, err := writeTime(smpptime.Absolute, p.ScheduleDeliveryTime)
+	out, err := appendTime(out, smpptime.Absolute, p.ScheduleDeliveryTime)
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
-	out = append(out, tm...)
-	tm, err = writeTime(smpptime.Absolute, p.ValidityPeriod)
+	out, err = appendTime(out, smpptime.Absolute, p.ValidityPeriod)
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
-	out = append(out, tm...)
-	l := len(p.ShortMessage)
 	out = append(out, p.RegisteredDelivery.Byte(), byte(p.ReplaceIfPresentFlag), byte(p.DataCoding), byte(p.SmDefaultMsgID), byte(l))
-	if l > 0 {
-		out = append(out, []byte(p.ShortMessage)...)
-	}
+	out = append(out, p.ShortMessage...)
 	if p.Options == nil {
 		return out, nil
 	}
-	opts, err := p.Options.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	return append(out, opts...), nil
+	return p.Options.AppendBinary(out)
 }
 
+// minMessageBodyLen is the shortest submit_sm and deliver_sm body: five
+// empty C-Octet strings of one null byte each (service_type, source_addr,
+// destination_addr, schedule_delivery_time and validity_period) and twelve
+// single byte integers from source_addr_ton to sm_length.
+const minMessageBodyLen = 17
+
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
 func (p *SubmitSm) UnmarshalBinary(body []byte) error {
-	if len(body) < 25 {
-		return fmt.Errorf("smpp/pdu: submit_sm body too short: %d", len(body))
+	if len(body) < minMessageBodyLen {
+		return bodyTooShort("submit_sm", len(body))
 	}
 	buf := newBuffer(body)
 	res, err := buf.ReadCString(6)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding service_type %s", err)
+		return decodeError(ServiceTypeFld, err)
 	}
 	p.ServiceType = string(res)
 	b, err := buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding source_addr_ton %s", err)
+		return decodeError(SourceAddrTonFld, err)
 	}
 	p.SourceAddrTon = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding source_addr_npi %s", err)
+		return decodeError(SourceAddrNpiFld, err)
 	}
 	p.SourceAddrNpi = int(b)
 	res, err = buf.ReadCString(21)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding source_addr %s", err)
+		return decodeError(SourceAddrFld, err)
 	}
 	p.SourceAddr = string(res)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding dest_addr_ton %s", err)
+		return decodeError(DestAddrTonFld, err)
 	}
 	p.DestAddrTon = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding dest_addr_npi %s", err)
+		return decodeError(DestAddrNpiFld, err)
 	}
 	p.DestAddrNpi = int(b)
 	res, err = buf.ReadCString(21)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding dest_addr %s", err)
+		return decodeError(DestinationAddrFld, err)
 	}
 	p.DestinationAddr = string(res)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding esm_class %s", err)
+		return decodeError(EsmClassFld, err)
 	}
 	p.EsmClass = ParseEsmClass(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding protocol_id %s", err)
+		return decodeError(ProtocolIDFld, err)
 	}
 	p.ProtocolID = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding priority_flag %s", err)
+		return decodeError(PriorityFlagFld, err)
 	}
 	p.PriorityFlag = int(b)
 	res, err = buf.ReadCString(17)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding schedule_delivery_time %s", err)
+		return decodeError(ScheduleDeliveryTimeFld, err)
 	}
 	t, err := smpptime.Parse(res)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding schedule_delivery_time %s", err)
+		return decodeError(ScheduleDeliveryTimeFld, err)
 	}
 	p.ScheduleDeliveryTime = t
 	res, err = buf.ReadCString(17)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding validity_period %s", err)
+		return decodeError(ValidityPeriodFld, err)
 	}
 	t, err = smpptime.Parse(res)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding validity_period %s", err)
+		return decodeError(ValidityPeriodFld, err)
 	}
 	p.ValidityPeriod = t
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding registered_delivery %s", err)
+		return decodeError(RegisteredDeliveryFld, err)
 	}
 	p.RegisteredDelivery = ParseRegisteredDelivery(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding replace_if_present_flag %s", err)
+		return decodeError(ReplaceIfPresentFlagFld, err)
 	}
 	p.ReplaceIfPresentFlag = int(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding data_coding %s", err)
+		return decodeError(DataCodingFld, err)
 	}
-	p.DataCoding = int(b)
+	p.DataCoding = DataCoding(b)
 	b, err = buf.ReadByte()
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding sm_default_msg_id %s", err)
+		return decodeError(SmDefaultMsgIDFld, err)
 	}
 	p.SmDefaultMsgID = int(b)
 	sm, err := buf.ReadString(254)
 	if err != nil {
-		return fmt.Errorf("smpp/pdu: decoding short_message %s", err)
+		return decodeError(ShortMessageFld, err)
 	}
 	p.ShortMessage = sm
 	if buf.Len() == 0 {
@@ -203,9 +218,19 @@ func (p SubmitSmResp) CommandID() CommandID {
 	return SubmitSmRespID
 }
 
+// Validate implements pdu.Validator interface.
+func (p SubmitSmResp) Validate() error {
+	return validateMessageIDResp(SubmitSmRespID, p.MessageID, p.Options)
+}
+
 // MarshalBinary implements encoding.BinaryMarshaler interface.
 func (p SubmitSmResp) MarshalBinary() ([]byte, error) {
-	return cStringOptsRespMarshal(p.MessageID, p.Options)
+	return p.AppendBinary(nil)
+}
+
+// AppendBinary implements pdu.Appender interface.
+func (p SubmitSmResp) AppendBinary(dst []byte) ([]byte, error) {
+	return cStringOptsRespAppend(dst, p.MessageID, p.Options)
 }
 
 // UnmarshalBinary implements encoding.BinaryUnmarshaler interface.
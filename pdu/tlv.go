@@ -0,0 +1,317 @@
+package pdu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// TagType describes how TLV value is encoded.
+type TagType int
+
+// TLV value types.
+const (
+	// IntTag is unsigned big endian integer of 1, 2 or 4 octets.
+	IntTag TagType = iota
+	// CStringTag is null terminated string.
+	CStringTag
+	// OctetStringTag is string or binary data without terminating null.
+	OctetStringTag
+	// NoValueTag is tag without value, its presence carries the meaning.
+	NoValueTag
+)
+
+// TagDef describes TLV tag encoding and where it can be used.
+type TagDef struct {
+	Tag  TagID
+	Name string
+	Type TagType
+	// MinLen and MaxLen limit length of the value in octets.
+	MinLen int
+	MaxLen int
+	// MaxValue is upper limit of integer values, zero if unrestricted.
+	MaxValue int
+	// PDUs that are allowed to carry the tag, nil if any.
+	PDUs []CommandID
+	// Multiple is set if the tag can occur more than once in the PDU.
+	Multiple bool
+	// Decode converts raw value to the value returned by Options.Value.
+	// If nil value is decoded according to the Type.
+	Decode func(val []byte) (interface{}, error)
+}
+
+var (
+	submitPDUs  = []CommandID{SubmitSmID, SubmitMultiID, DataSmID}
+	messagePDUs = []CommandID{SubmitSmID, SubmitMultiID, DeliverSmID, DataSmID}
+	deliverPDUs = []CommandID{DeliverSmID, DataSmID}
+	dataSmPDUs  = []CommandID{DataSmID}
+	dataResp    = []CommandID{DataSmRespID}
+	bindResp    = []CommandID{BindReceiverRespID, BindTransmitterRespID, BindTransceiverRespID}
+)
+
+// Range of tag values reserved for vendor specific TLVs.
+const (
+	MinVendorTag TagID = 0x1400
+	MaxVendorTag TagID = 0x3FFF
+)
+
+var (
+	tagMu   sync.RWMutex
+	tagDefs = map[TagID]TagDef{}
+)
+
+func init() {
+	for _, def := range []TagDef{
+		{TagDestAddrSubUnit, "dest_addr_subunit", IntTag, 1, 1, 4, submitPDUs, false, nil},
+		{TagDestNetworkType, "dest_network_type", IntTag, 1, 1, 8, dataSmPDUs, false, nil},
+		{TagDestBearerType, "dest_bearer_type", IntTag, 1, 1, 8, dataSmPDUs, false, nil},
+		{TagDestTelematicsID, "dest_telematics_id", IntTag, 2, 2, 0, dataSmPDUs, false, nil},
+		{TagSourceAddrSubunit, "source_addr_subunit", IntTag, 1, 1, 4, submitPDUs, false, nil},
+		{TagSourceNetworkType, "source_network_type", IntTag, 1, 1, 8, dataSmPDUs, false, nil},
+		{TagSourceBearerType, "source_bearer_type", IntTag, 1, 1, 8, dataSmPDUs, false, nil},
+		{TagSourceTelematicsID, "source_telematics_id", IntTag, 1, 2, 0, dataSmPDUs, false, nil},
+		{TagQosTimeToLive, "qos_time_to_live", IntTag, 4, 4, 0, dataSmPDUs, false, nil},
+		{TagPayloadType, "payload_type", IntTag, 1, 1, 1, messagePDUs, false, nil},
+		{TagAdditionalStatusInfoTe, "additional_status_info_text", CStringTag, 1, 256, 0, dataResp, false, nil},
+		{TagReceiptedMessageID, "receipted_message_id", CStringTag, 1, 65, 0, deliverPDUs, false, nil},
+		{TagMsMsgWaitFacilities, "ms_msg_wait_facilities", IntTag, 1, 1, 0, submitPDUs, false, nil},
+		{TagPrivacyIndicator, "privacy_indicator", IntTag, 1, 1, 3, messagePDUs, false, nil},
+		{TagSourceSubaddress, "source_subaddress", OctetStringTag, 2, 23, 0, messagePDUs, false, nil},
+		{TagDestSubaddress, "dest_subaddress", OctetStringTag, 2, 23, 0, messagePDUs, false, nil},
+		{TagUserMessageReference, "user_message_reference", IntTag, 2, 2, 0, messagePDUs, false, nil},
+		{TagUserResponseCode, "user_response_code", IntTag, 1, 1, 0, messagePDUs, false, nil},
+		{TagSourcePort, "source_port", IntTag, 2, 2, 0, messagePDUs, false, nil},
+		{TagDestinationPort, "destination_port", IntTag, 2, 2, 0, messagePDUs, false, nil},
+		{TagSarMsgRefNum, "sar_msg_ref_num", IntTag, 2, 2, 0, messagePDUs, false, nil},
+		{TagLanguageIndicator, "language_indicator", IntTag, 1, 1, 5, messagePDUs, false, nil},
+		{TagSarTotalSegments, "sar_total_segments", IntTag, 1, 1, 0, messagePDUs, false, nil},
+		{TagSarSegmentSeqnum, "sar_segment_seqnum", IntTag, 1, 1, 0, messagePDUs, false, nil},
+		{TagScInterfaceVersion, "sc_interface_version", IntTag, 1, 1, 0, bindResp, false, nil},
+		{TagCallbackNumPresInd, "callback_num_pres_ind", IntTag, 1, 1, 0x0F, submitPDUs, true, nil},
+		{TagCallbackNumA, "callback_num_atag", OctetStringTag, 0, 65, 0, submitPDUs, true, nil},
+		{TagNumberOfMessages, "number_of_messages", IntTag, 1, 1, 99, submitPDUs, false, nil},
+		{TagCallbackNum, "callback_num", OctetStringTag, 4, 19, 0, messagePDUs, true, nil},
+		{TagDpfResult, "dpf_result", IntTag, 1, 1, 1, dataResp, false, nil},
+		{TagSetDPF, "set_dpf", IntTag, 1, 1, 1, dataSmPDUs, false, nil},
+		{TagMsAvailabilityStatus, "ms_availability_status", IntTag, 1, 1, 2, []CommandID{AlertNotificationID}, false, nil},
+		{TagNetworkErrorCode, "network_error_code", OctetStringTag, 3, 3, 0, append([]CommandID{DataSmRespID}, deliverPDUs...), false, nil},
+		{TagMessagePayload, "message_payload", OctetStringTag, 0, 0xFFFF, 0, messagePDUs, false, nil},
+		{TagDeliveryFailureReason, "delivery_failure_reason", IntTag, 1, 1, 3, dataResp, false, nil},
+		{TagMoreMessagesToSend, "more_messages_to_send", IntTag, 1, 1, 1, submitPDUs, false, nil},
+		{TagMessageState, "message_state", IntTag, 1, 1, 8, deliverPDUs, false, nil},
+		{TagUssdServiceOp, "ussd_service_op", IntTag, 1, 1, 0, messagePDUs, false, nil},
+		{TagDisplayTime, "display_time", IntTag, 1, 1, 2, submitPDUs, false, nil},
+		{TagSmsSignal, "sms_signal", IntTag, 2, 2, 0, submitPDUs, false, nil},
+		{TagMsValidity, "ms_validity", IntTag, 1, 1, 3, submitPDUs, false, nil},
+		{TagAlertOnMessageDeliv, "alert_on_message_delivery", NoValueTag, 0, 0, 0, submitPDUs, false, nil},
+		{TagItsReplyType, "its_reply_type", IntTag, 1, 1, 8, submitPDUs, false, nil},
+		{TagItsSessionInfo, "its_session_info", OctetStringTag, 2, 2, 0, messagePDUs, false, nil},
+	} {
+		tagDefs[def.Tag] = def
+	}
+}
+
+// RegisterTag adds vendor specific tag definition so the tag is named,
+// validated and decoded like the ones defined by the specification.
+// Tag has to be in the vendor specific range and can be registered once.
+// Zero MaxLen is treated as unlimited unless Type is NoValueTag.
+func RegisterTag(def TagDef) error {
+	if def.Tag < MinVendorTag || def.Tag > MaxVendorTag {
+		return fmt.Errorf("smpp/pdu: tag %s is not in vendor specific range", def.Tag)
+	}
+	if def.Name == "" {
+		return fmt.Errorf("smpp/pdu: tag %s registered without name", def.Tag)
+	}
+	if def.MaxLen == 0 && def.Type != NoValueTag {
+		def.MaxLen = 0xFFFF
+	}
+	tagMu.Lock()
+	defer tagMu.Unlock()
+	if old, ok := tagDefs[def.Tag]; ok {
+		return fmt.Errorf("smpp/pdu: tag 0x%04X already registered as %s", uint16(def.Tag), old.Name)
+	}
+	tagDefs[def.Tag] = def
+	return nil
+}
+
+// unregisterTag removes vendor specific tag definition.
+func unregisterTag(tag TagID) {
+	tagMu.Lock()
+	defer tagMu.Unlock()
+	if tag >= MinVendorTag && tag <= MaxVendorTag {
+		delete(tagDefs, tag)
+	}
+}
+
+// LookupTag returns definition of the tag if it's known.
+func LookupTag(tag TagID) (TagDef, bool) {
+	tagMu.RLock()
+	defer tagMu.RUnlock()
+	def, ok := tagDefs[tag]
+	return def, ok
+}
+
+// tagNames are names of the tags defined by the specification.
+var tagNames = map[TagID]string{
+	TagDestAddrSubUnit:        "TagDestAddrSubUnit",
+	TagDestNetworkType:        "TagDestNetworkType",
+	TagDestBearerType:         "TagDestBearerType",
+	TagDestTelematicsID:       "TagDestTelematicsID",
+	TagSourceAddrSubunit:      "TagSourceAddrSubunit",
+	TagSourceNetworkType:      "TagSourceNetworkType",
+	TagSourceBearerType:       "TagSourceBearerType",
+	TagSourceTelematicsID:     "TagSourceTelematicsID",
+	TagQosTimeToLive:          "TagQosTimeToLive",
+	TagPayloadType:            "TagPayloadType",
+	TagAdditionalStatusInfoTe: "TagAdditionalStatusInfoTe",
+	TagReceiptedMessageID:     "TagReceiptedMessageID",
+	TagMsMsgWaitFacilities:    "TagMsMsgWaitFacilities",
+	TagPrivacyIndicator:       "TagPrivacyIndicator",
+	TagSourceSubaddress:       "TagSourceSubaddress",
+	TagDestSubaddress:         "TagDestSubaddress",
+	TagUserMessageReference:   "TagUserMessageReference",
+	TagUserResponseCode:       "TagUserResponseCode",
+	TagSourcePort:             "TagSourcePort",
+	TagDestinationPort:        "TagDestinationPort",
+	TagSarMsgRefNum:           "TagSarMsgRefNum",
+	TagLanguageIndicator:      "TagLanguageIndicator",
+	TagSarTotalSegments:       "TagSarTotalSegments",
+	TagSarSegmentSeqnum:       "TagSarSegmentSeqnum",
+	TagScInterfaceVersion:     "TagScInterfaceVersion",
+	TagCallbackNumPresInd:     "TagCallbackNumPresInd",
+	TagCallbackNumA:           "TagCallbackNumA",
+	TagNumberOfMessages:       "TagNumberOfMessages",
+	TagCallbackNum:            "TagCallbackNum",
+	TagDpfResult:              "TagDpfResult",
+	TagSetDPF:                 "TagSetDPF",
+	TagMsAvailabilityStatus:   "TagMsAvailabilityStatus",
+	TagNetworkErrorCode:       "TagNetworkErrorCode",
+	TagMessagePayload:         "TagMessagePayload",
+	TagDeliveryFailureReason:  "TagDeliveryFailureReason",
+	TagMoreMessagesToSend:     "TagMoreMessagesToSend",
+	TagMessageState:           "TagMessageState",
+	TagUssdServiceOp:          "TagUssdServiceOp",
+	TagDisplayTime:            "TagDisplayTime",
+	TagSmsSignal:              "TagSmsSignal",
+	TagMsValidity:             "TagMsValidity",
+	TagAlertOnMessageDeliv:    "TagAlertOnMessageDeliv",
+	TagItsReplyType:           "TagItsReplyType",
+	TagItsSessionInfo:         "TagItsSessionInfo",
+}
+
+// String returns name of the registered vendor specific tag, constant name
+// of the tag defined by the specification or hex value of unknown tag.
+func (t TagID) String() string {
+	if t >= MinVendorTag && t <= MaxVendorTag {
+		if def, ok := LookupTag(t); ok {
+			return def.Name
+		}
+	}
+	if name, ok := tagNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TagID(0x%04X)", uint16(t))
+}
+
+// Allowed reports whether PDU with given command id may carry the tag.
+func (d TagDef) Allowed(id CommandID) bool {
+	if d.PDUs == nil {
+		return true
+	}
+	for _, allowed := range d.PDUs {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks if value length and content conform to the definition.
+// Errors are of type ValidationError with StatusInvParLen or StatusInvOptParamVal.
+func (d TagDef) Validate(val []byte) error {
+	if len(val) < d.MinLen || len(val) > d.MaxLen {
+		return ValidationError{
+			Field:  d.Name,
+			Status: StatusInvParLen,
+			Reason: fmt.Sprintf("length %d out of range %d-%d", len(val), d.MinLen, d.MaxLen),
+		}
+	}
+	switch d.Type {
+	case IntTag:
+		if d.MaxValue > 0 && decodeInt(val) > d.MaxValue {
+			return ValidationError{
+				Field:  d.Name,
+				Status: StatusInvOptParamVal,
+				Reason: fmt.Sprintf("value %d over upper limit %d", decodeInt(val), d.MaxValue),
+			}
+		}
+	case CStringTag:
+		for i, b := range val {
+			if (b == 0) != (i == len(val)-1) {
+				return ValidationError{
+					Field:  d.Name,
+					Status: StatusInvOptParamVal,
+					Reason: "c string is not null terminated",
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that all known tags have valid values and are allowed
+// in the PDU with given command id. Not allowed tags are reported with
+// StatusOptParNotAllwd.
+func (o *Options) Validate(id CommandID) error {
+	for _, f := range o.fields {
+		def, ok := LookupTag(f.tag)
+		if !ok {
+			continue
+		}
+		if !def.Allowed(id) {
+			return ValidationError{
+				Field:  def.Name,
+				Status: StatusOptParNotAllwd,
+				Reason: fmt.Sprintf("not allowed in %s", id),
+			}
+		}
+		if err := def.Validate(f.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Value decodes the raw value according to the definition.
+func (d TagDef) Value(val []byte) (interface{}, error) {
+	if d.Decode != nil {
+		return d.Decode(val)
+	}
+	switch d.Type {
+	case IntTag:
+		if len(val) != 1 && len(val) != 2 && len(val) != 4 {
+			return nil, fmt.Errorf("smpp/pdu: invalid integer length of %s (%d)", d.Name, len(val))
+		}
+		return decodeInt(val), nil
+	case CStringTag:
+		if len(val) == 0 {
+			return "", nil
+		}
+		return string(val[:len(val)-1]), nil
+	case NoValueTag:
+		return true, nil
+	}
+	return val, nil
+}
+
+func decodeInt(b []byte) int {
+	switch len(b) {
+	case 1:
+		return int(b[0])
+	case 2:
+		return int(binary.BigEndian.Uint16(b))
+	case 4:
+		return int(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
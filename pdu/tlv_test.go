@@ -0,0 +1,164 @@
+package pdu
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestOptionsUnmarshalValidation(t *testing.T) {
+	tt := []struct {
+		hex    string
+		status Status
+	}{
+		{"020A000100", StatusInvParLen},                     // source_port with one byte
+		{"04270002000A", StatusInvParLen},                   // message_state with two bytes
+		{"042700010A", StatusInvOptParamVal},                // message_state over 8
+		{"001E0003414243", StatusInvOptParamVal},            // receipted_message_id without null
+		{"130C000101", StatusInvParLen},                     // alert_on_message_delivery with value
+		{"0421000102", StatusInvOptParamVal},                // set_dpf over 1
+		{"020A00020001020A00020002", StatusInvOptParStream}, // repeated source_port
+	}
+	for _, row := range tt {
+		b, _ := hex.DecodeString(row.hex)
+		err := NewOptions().UnmarshalBinary(b)
+		if status, ok := ErrorStatus(err); !ok || status != row.status {
+			t.Errorf("%s => %v expected status %s", row.hex, err, row.status)
+		}
+	}
+	b, _ := hex.DecodeString("020A00021582042700010299990001FF130C000099990001EE")
+	o := NewOptions()
+	if err := o.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if o.SourcePort() != 0x1582 || o.MessageState() != 2 || !o.AlertOnMessageDelivery() {
+		t.Errorf("decoded options %+v", o)
+	}
+	if vals := o.GetAll(0x9999); len(vals) != 2 || vals[1][0] != 0xEE {
+		t.Errorf("repeated unknown tag not preserved %X", vals)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	o := NewOptions().SetReceiptedMessageID("abc").SetMessageState(2)
+	if err := o.Validate(DeliverSmID); err != nil {
+		t.Errorf("valid options %v", err)
+	}
+	err := o.Validate(SubmitSmID)
+	if status, ok := ErrorStatus(err); !ok || status != StatusOptParNotAllwd {
+		t.Errorf("receipt fields in submit_sm %v", err)
+	}
+	o = NewOptions().SetNumberOfMessages(100)
+	err = o.Validate(SubmitSmID)
+	if status, ok := ErrorStatus(err); !ok || status != StatusInvOptParamVal {
+		t.Errorf("number_of_messages over limit %v", err)
+	}
+}
+
+func TestOptionsTypedAccessors(t *testing.T) {
+	o := NewOptions().
+		SetQosTimeToLive(86400).
+		SetNetworkErrorCode(3, 0x0103).
+		SetDestTelematicsID(0x0102).
+		SetAdditionalStatusInfoText("info")
+	if v := o.QosTimeToLive(); v != 86400 {
+		t.Errorf("qos_time_to_live %d", v)
+	}
+	if typ, code := o.NetworkErrorCode(); typ != 3 || code != 0x0103 {
+		t.Errorf("network_error_code %d %d", typ, code)
+	}
+	if v := o.DestTelematicsID(); v != 0x0102 {
+		t.Errorf("dest_telematics_id %d", v)
+	}
+	if v := o.AdditionalStatusInfoText(); v != "info" {
+		t.Errorf("additional_status_info_text %q", v)
+	}
+	if _, ok := NewOptions().Set(TagSourcePort, []byte{1}).GetDouble(TagSourcePort); ok {
+		t.Errorf("short value decoded as double")
+	}
+}
+
+func TestRegisterTag(t *testing.T) {
+	const tagBillingID TagID = 0x1403
+	if s := tagBillingID.String(); s != "TagID(0x1403)" {
+		t.Errorf("unregistered tag %q", s)
+	}
+	err := RegisterTag(TagDef{Tag: tagBillingID, Name: "billing_id", Type: CStringTag, MinLen: 1, MaxLen: 17})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unregisterTag(tagBillingID) })
+	if err := RegisterTag(TagDef{Tag: tagBillingID, Name: "other"}); err == nil {
+		t.Errorf("expected error registering tag twice")
+	}
+	if err := RegisterTag(TagDef{Tag: TagSourcePort, Name: "port"}); err == nil {
+		t.Errorf("expected error registering tag outside vendor range")
+	}
+	if s := TagReceiptedMessageID.String(); s != "TagReceiptedMessageID" {
+		t.Errorf("String() of known tag %q", s)
+	}
+	if s := tagBillingID.String(); s != "billing_id" {
+		t.Errorf("registered tag %q", s)
+	}
+	o := NewOptions()
+	b, _ := hex.DecodeString("14030002414214030003414200")
+	if err := o.UnmarshalBinary(b[:6]); err == nil {
+		t.Errorf("expected validation error for vendor tag")
+	}
+	if err := o.UnmarshalBinary(b[6:]); err != nil {
+		t.Fatal(err)
+	}
+	if val, err := o.Value(tagBillingID); err != nil || val != "AB" {
+		t.Errorf("vendor tag value %v %v", val, err)
+	}
+	o.SetSourcePort(0x10)
+	if s := o.String(); s != `billing_id="AB" TagSourcePort=16` {
+		t.Errorf("options string %s", s)
+	}
+}
+
+func TestOptionsOrder(t *testing.T) {
+	o := NewOptions().
+		SetSourcePort(1).
+		SetDestinationPort(2).
+		Add(TagCallbackNum, []byte("0001")).
+		Add(TagCallbackNum, []byte("0002")).
+		SetMessagePayload("payload")
+	o.SetSourcePort(3)
+	o.Add(TagDestinationPort, []byte{0, 4})
+	b, err := o.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "020A00020003020B0002000403810004303030310381000430303032042400077061796C6F6164"
+	if hex.EncodeToString(b) != strings.ToLower(expected) {
+		t.Errorf("marshal %X\nexpected %s", b, expected)
+	}
+	decoded := NewOptions()
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(o) || decoded.Len() != 5 {
+		t.Errorf("decoded %s\nexpected %s", decoded, o)
+	}
+	if vals := decoded.GetAll(TagCallbackNum); len(vals) != 2 || string(vals[1]) != "0002" {
+		t.Errorf("repeated callback_num %q", vals)
+	}
+	c := o.Clone()
+	c.Delete(TagCallbackNum, TagSourcePort)
+	if c.Len() != 2 || o.Len() != 5 || c.Equal(o) {
+		t.Errorf("clone %s original %s", c, o)
+	}
+	var tags []TagID
+	c.Range(func(tag TagID, val []byte) bool {
+		tags = append(tags, tag)
+		return true
+	})
+	if len(tags) != 2 || tags[0] != TagDestinationPort || tags[1] != TagMessagePayload {
+		t.Errorf("range %v", tags)
+	}
+	long := NewOptions().Set(TagMessagePayload, make([]byte, 0x10000))
+	if _, err := long.MarshalBinary(); err == nil {
+		t.Errorf("marshaled value over 65535 bytes")
+	}
+}
@@ -0,0 +1,339 @@
+package pdu
+
+import (
+	"errors"
+	"fmt"
+)
+
+// UDH information element identifiers.
+const (
+	IEConcat8      = 0x00 // Concatenated short messages, 8-bit reference number
+	IESpecialSMS   = 0x01 // Special SMS Message Indication
+	IEPort8        = 0x04 // Application port addressing scheme, 8 bit address
+	IEPort16       = 0x05 // Application port addressing scheme, 16 bit address
+	IEConcat16     = 0x08 // Concatenated short messages, 16-bit reference number
+	IESingleShift  = 0x24 // National Language Single Shift
+	IELockingShift = 0x25 // National Language Locking Shift
+)
+
+// National language identifiers used with single and locking shift elements.
+const (
+	LangTurkish    = 0x01
+	LangSpanish    = 0x02
+	LangPortuguese = 0x03
+	LangBengali    = 0x04
+	LangGujarati   = 0x05
+	LangHindi      = 0x06
+	LangKannada    = 0x07
+	LangMalayalam  = 0x08
+	LangOriya      = 0x09
+	LangPunjabi    = 0x0A
+	LangTamil      = 0x0B
+	LangTelugu     = 0x0C
+	LangUrdu       = 0x0D
+)
+
+// InformationElement is a single element of the user data header.
+// Elements unknown to this package are preserved as they are.
+type InformationElement struct {
+	ID   byte
+	Data []byte
+}
+
+// UDH is user data header with parsed information elements.
+// It is prepended to the short message content when UDHI flag is set
+// in esm_class.
+type UDH []InformationElement
+
+// ParseUDH takes input bytes and separates them into parsed UDH and content.
+func ParseUDH(b []byte) (UDH, []byte, error) {
+	h, content, err := SeparateUDH(b)
+	if err != nil {
+		return nil, b, err
+	}
+	var udh UDH
+	for i := 1; i < len(h); {
+		if i+1 >= len(h) {
+			return nil, b, errors.New("smpp: udh information element truncated")
+		}
+		l := int(h[i+1])
+		if i+2+l > len(h) {
+			return nil, b, fmt.Errorf("smpp: invalid udh information element length 0x%02X %d", h[i], l)
+		}
+		udh = append(udh, InformationElement{ID: h[i], Data: h[i+2 : i+2+l]})
+		i += 2 + l
+	}
+	return udh, content, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface.
+// Output starts with the UDH length octet.
+func (u UDH) MarshalBinary() ([]byte, error) {
+	out := []byte{0}
+	for _, ie := range u {
+		if len(ie.Data) > 0xFF {
+			return nil, fmt.Errorf("smpp: udh information element 0x%02X too long", ie.ID)
+		}
+		out = append(out, ie.ID, byte(len(ie.Data)))
+		out = append(out, ie.Data...)
+	}
+	if len(out) > 0xFF {
+		return nil, errors.New("smpp: udh too long")
+	}
+	out[0] = byte(len(out) - 1)
+	return out, nil
+}
+
+// Len returns encoded length of the UDH including the length octet.
+func (u UDH) Len() int {
+	l := 1
+	for _, ie := range u {
+		l += 2 + len(ie.Data)
+	}
+	return l
+}
+
+// Get returns first information element with the given identifier.
+func (u UDH) Get(id byte) (InformationElement, bool) {
+	for _, ie := range u {
+		if ie.ID == id {
+			return ie, true
+		}
+	}
+	return InformationElement{}, false
+}
+
+// Set replaces the information element with the same identifier or appends
+// a new one if there is none.
+func (u *UDH) Set(id byte, data []byte) *UDH {
+	for i, ie := range *u {
+		if ie.ID == id {
+			(*u)[i].Data = data
+			return u
+		}
+	}
+	*u = append(*u, InformationElement{ID: id, Data: data})
+	return u
+}
+
+// Delete removes all information elements with given identifiers.
+func (u *UDH) Delete(ids ...byte) *UDH {
+	out := (*u)[:0]
+	for _, ie := range *u {
+		keep := true
+		for _, id := range ids {
+			if ie.ID == id {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, ie)
+		}
+	}
+	*u = out
+	return u
+}
+
+// Concat describes concatenated short message part.
+type Concat struct {
+	Ref   int  // Reference number same for all parts of the message.
+	Total int  // Total number of parts.
+	Seq   int  // Sequence number of this part starting from 1.
+	Wide  bool // Use 16-bit reference number.
+}
+
+// Concat returns concatenation element if present.
+func (u UDH) Concat() (Concat, bool) {
+	for _, ie := range u {
+		switch {
+		case ie.ID == IEConcat8 && len(ie.Data) == 3:
+			return Concat{Ref: int(ie.Data[0]), Total: int(ie.Data[1]), Seq: int(ie.Data[2])}, true
+		case ie.ID == IEConcat16 && len(ie.Data) == 4:
+			return Concat{
+				Ref:   int(ie.Data[0])<<8 | int(ie.Data[1]),
+				Total: int(ie.Data[2]),
+				Seq:   int(ie.Data[3]),
+				Wide:  true,
+			}, true
+		}
+	}
+	return Concat{}, false
+}
+
+// SetConcat sets concatenation element replacing existing one.
+func (u *UDH) SetConcat(c Concat) *UDH {
+	u.Delete(IEConcat8, IEConcat16)
+	if c.Wide {
+		return u.Set(IEConcat16, []byte{byte(c.Ref >> 8), byte(c.Ref), byte(c.Total), byte(c.Seq)})
+	}
+	return u.Set(IEConcat8, []byte{byte(c.Ref), byte(c.Total), byte(c.Seq)})
+}
+
+// Ports describes application port addressing.
+type Ports struct {
+	Src  int
+	Dst  int
+	Wide bool // Use 16-bit port numbers.
+}
+
+// Ports returns application port addressing element if present.
+func (u UDH) Ports() (Ports, bool) {
+	for _, ie := range u {
+		switch {
+		case ie.ID == IEPort8 && len(ie.Data) == 2:
+			return Ports{Dst: int(ie.Data[0]), Src: int(ie.Data[1])}, true
+		case ie.ID == IEPort16 && len(ie.Data) == 4:
+			return Ports{
+				Dst:  int(ie.Data[0])<<8 | int(ie.Data[1]),
+				Src:  int(ie.Data[2])<<8 | int(ie.Data[3]),
+				Wide: true,
+			}, true
+		}
+	}
+	return Ports{}, false
+}
+
+// SetPorts sets application port addressing element replacing existing one.
+// 16-bit addressing is used if Wide is set or any of the ports doesn't fit
+// into 8 bits.
+func (u *UDH) SetPorts(p Ports) *UDH {
+	u.Delete(IEPort8, IEPort16)
+	if p.Wide || p.Src > 0xFF || p.Dst > 0xFF {
+		return u.Set(IEPort16, []byte{byte(p.Dst >> 8), byte(p.Dst), byte(p.Src >> 8), byte(p.Src)})
+	}
+	return u.Set(IEPort8, []byte{byte(p.Dst), byte(p.Src)})
+}
+
+// IndicationType defines type of the waiting message.
+type IndicationType int
+
+// Message waiting indication types.
+const (
+	VoiceMailIndication IndicationType = 0x0
+	FaxIndication       IndicationType = 0x1
+	EmailIndication     IndicationType = 0x2
+	OtherIndication     IndicationType = 0x3
+)
+
+// SpecialSMSIndication describes number of messages waiting of the given type.
+type SpecialSMSIndication struct {
+	Type  IndicationType
+	Count int  // Number of waiting messages, zero clears the indication.
+	Store bool // Store the message after updating indication.
+}
+
+// SpecialSMS returns all special SMS message indication elements.
+func (u UDH) SpecialSMS() []SpecialSMSIndication {
+	var out []SpecialSMSIndication
+	for _, ie := range u {
+		if ie.ID != IESpecialSMS || len(ie.Data) != 2 {
+			continue
+		}
+		out = append(out, SpecialSMSIndication{
+			Type:  IndicationType(ie.Data[0] & 0x03),
+			Count: int(ie.Data[1]),
+			Store: ie.Data[0]&0x80 != 0,
+		})
+	}
+	return out
+}
+
+// AddSpecialSMS adds special SMS message indication element replacing the
+// one with the same indication type.
+func (u *UDH) AddSpecialSMS(ind SpecialSMSIndication) *UDH {
+	b := byte(ind.Type) & 0x03
+	if ind.Store {
+		b |= 0x80
+	}
+	for i, ie := range *u {
+		if ie.ID == IESpecialSMS && len(ie.Data) == 2 && ie.Data[0]&0x03 == byte(ind.Type) {
+			(*u)[i].Data = []byte{b, byte(ind.Count)}
+			return u
+		}
+	}
+	*u = append(*u, InformationElement{ID: IESpecialSMS, Data: []byte{b, byte(ind.Count)}})
+	return u
+}
+
+// SingleShift returns national language single shift table identifier.
+func (u UDH) SingleShift() (int, bool) {
+	return u.single(IESingleShift)
+}
+
+// SetSingleShift sets national language single shift table.
+func (u *UDH) SetSingleShift(lang int) *UDH {
+	return u.Set(IESingleShift, []byte{byte(lang)})
+}
+
+// LockingShift returns national language locking shift table identifier.
+func (u UDH) LockingShift() (int, bool) {
+	return u.single(IELockingShift)
+}
+
+// SetLockingShift sets national language locking shift table.
+func (u *UDH) SetLockingShift(lang int) *UDH {
+	return u.Set(IELockingShift, []byte{byte(lang)})
+}
+
+func (u UDH) single(id byte) (int, bool) {
+	ie, ok := u.Get(id)
+	if !ok || len(ie.Data) != 1 {
+		return 0, false
+	}
+	return int(ie.Data[0]), true
+}
+
+func messageUDH(ec EsmClass, sm []byte) (UDH, []byte, error) {
+	if ec.Feature&UDHIEsmFeat == 0 {
+		return nil, sm, nil
+	}
+	return ParseUDH(sm)
+}
+
+func withUDH(ec *EsmClass, u UDH, content []byte) ([]byte, error) {
+	if len(u) == 0 {
+		ec.Feature &^= UDHIEsmFeat
+		return content, nil
+	}
+	b, err := u.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	ec.Feature |= UDHIEsmFeat
+	return append(b, content...), nil
+}
+
+// UDH parses user data header from short message if UDHI flag is set and
+// returns it together with the remaining content.
+func (p SubmitSm) UDH() (UDH, []byte, error) {
+	return messageUDH(p.EsmClass, p.ShortMessage)
+}
+
+// SetUDH sets short message to the encoded UDH followed by content and
+// updates UDHI flag accordingly.
+func (p *SubmitSm) SetUDH(u UDH, content []byte) error {
+	sm, err := withUDH(&p.EsmClass, u, content)
+	if err != nil {
+		return err
+	}
+	p.ShortMessage = sm
+	return nil
+}
+
+// UDH parses user data header from short message if UDHI flag is set and
+// returns it together with the remaining content.
+func (p DeliverSm) UDH() (UDH, []byte, error) {
+	return messageUDH(p.EsmClass, p.ShortMessage)
+}
+
+// SetUDH sets short message to the encoded UDH followed by content and
+// updates UDHI flag accordingly.
+func (p *DeliverSm) SetUDH(u UDH, content []byte) error {
+	sm, err := withUDH(&p.EsmClass, u, content)
+	if err != nil {
+		return err
+	}
+	p.ShortMessage = sm
+	return nil
+}
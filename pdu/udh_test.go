@@ -0,0 +1,85 @@
+package pdu
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestParseUDH(t *testing.T) {
+	b, _ := hex.DecodeString("150504158200000003AA03010102800324010A4201FF74657374")
+	udh, content, err := ParseUDH(b)
+	if err != nil {
+		t.Fatalf("parse udh %v", err)
+	}
+	if string(content) != "test" {
+		t.Errorf("content %q expected %q", content, "test")
+	}
+	if p, ok := udh.Ports(); !ok || p != (Ports{Dst: 0x1582, Src: 0, Wide: true}) {
+		t.Errorf("ports %+v %v", p, ok)
+	}
+	if c, ok := udh.Concat(); !ok || c != (Concat{Ref: 0xAA, Total: 3, Seq: 1}) {
+		t.Errorf("concat %+v %v", c, ok)
+	}
+	ind := udh.SpecialSMS()
+	if len(ind) != 1 || ind[0] != (SpecialSMSIndication{Type: VoiceMailIndication, Count: 3, Store: true}) {
+		t.Errorf("special sms %+v", ind)
+	}
+	if lang, ok := udh.SingleShift(); !ok || lang != LangPunjabi {
+		t.Errorf("single shift %d %v", lang, ok)
+	}
+	if ie, ok := udh.Get(0x42); !ok || !bytes.Equal(ie.Data, []byte{0xFF}) {
+		t.Errorf("unknown element not preserved %+v", ie)
+	}
+	out, err := udh.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, b[:22]) {
+		t.Errorf("marshal udh %X expected %X", out, b[:22])
+	}
+}
+
+func TestParseInvalidUDH(t *testing.T) {
+	for _, h := range []string{"", "05", "0400030102", "03000501"} {
+		b, _ := hex.DecodeString(h)
+		if _, _, err := ParseUDH(b); err == nil {
+			t.Errorf("expected error for udh %q", h)
+		}
+	}
+}
+
+func TestSubmitSmUDHRoundTrip(t *testing.T) {
+	udh := UDH{}
+	udh.SetConcat(Concat{Ref: 0x1234, Total: 2, Seq: 2, Wide: true})
+	udh.SetPorts(Ports{Dst: 5, Src: 6})
+	sm := &SubmitSm{}
+	if err := sm.SetUDH(udh, []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+	if sm.EsmClass.Feature != UDHIEsmFeat {
+		t.Errorf("UDHI flag not set")
+	}
+	b, err := sm.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &DeliverSm{}
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	got, content, err := decoded.UDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, udh) || string(content) != "content" {
+		t.Errorf("round trip %+v %q expected %+v", got, content, udh)
+	}
+	if err := sm.SetUDH(nil, []byte("plain")); err != nil {
+		t.Fatal(err)
+	}
+	if sm.EsmClass.Feature != NoEsmFeat || string(sm.ShortMessage) != "plain" {
+		t.Errorf("clearing udh %+v", sm)
+	}
+}
@@ -0,0 +1,153 @@
+package pdu
+
+import "fmt"
+
+// Validator is implemented by PDUs that can check their fields against
+// limits defined by the specification.
+type Validator interface {
+	Validate() error
+}
+
+// Maximum size of C-Octet string fields including terminating null.
+const (
+	systemIDSize     = 16
+	passwordSize     = 9
+	systemTypeSize   = 13
+	addressRangeSize = 41
+	serviceTypeSize  = 6
+	addrSize         = 21
+	messageIDSize    = 65
+)
+
+// Maximum length of short_message field.
+const maxShortMessageLen = 254
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkCString(field, val string, size int, status Status) error {
+	if len(val) >= size {
+		return ValidationError{
+			Field:  field,
+			Status: status,
+			Reason: fmt.Sprintf("length %d over limit %d", len(val), size-1),
+		}
+	}
+	for i := 0; i < len(val); i++ {
+		if val[i] == 0 {
+			return ValidationError{Field: field, Status: status, Reason: "contains null character"}
+		}
+	}
+	return nil
+}
+
+func checkRange(field string, val, max int, status Status) error {
+	if val < 0 || val > max {
+		return ValidationError{
+			Field:  field,
+			Status: status,
+			Reason: fmt.Sprintf("value %d out of range 0-%d", val, max),
+		}
+	}
+	return nil
+}
+
+func checkTon(field string, ton int, status Status) error {
+	return checkRange(field, ton, 6, status)
+}
+
+func checkNpi(field string, npi int, status Status) error {
+	switch npi {
+	case 0, 1, 3, 4, 6, 8, 9, 10, 14, 18:
+		return nil
+	}
+	return ValidationError{
+		Field:  field,
+		Status: status,
+		Reason: fmt.Sprintf("unknown numbering plan %d", npi),
+	}
+}
+
+func checkEsmClass(ec EsmClass) error {
+	return firstError(
+		checkRange(EsmClassFld, ec.Mode, 0x03, StatusInvEsmClass),
+		checkRange(EsmClassFld, ec.Type, 0x0F, StatusInvEsmClass),
+		checkRange(EsmClassFld, ec.Feature, 0x03, StatusInvEsmClass),
+	)
+}
+
+func checkRegisteredDelivery(rd RegisteredDelivery) error {
+	return firstError(
+		checkRange(RegisteredDeliveryFld, rd.Receipt, FailDeliveryReceipt, StatusInvRegDlvFlg),
+		checkRange(RegisteredDeliveryFld, rd.SMEAck, AllSMEAck, StatusInvRegDlvFlg),
+		checkRange(RegisteredDeliveryFld, rd.InterNotification, YesInterNotification, StatusInvRegDlvFlg),
+	)
+}
+
+func checkShortMessage(sm []byte, opts *Options) error {
+	if len(sm) > maxShortMessageLen {
+		return ValidationError{
+			Field:  ShortMessageFld,
+			Status: StatusInvMsgLen,
+			Reason: fmt.Sprintf("length %d over limit %d", len(sm), maxShortMessageLen),
+		}
+	}
+	if len(sm) == 0 || opts == nil {
+		return nil
+	}
+	if _, ok := opts.Get(TagMessagePayload); ok {
+		return ValidationError{
+			Field:  ShortMessageFld,
+			Status: StatusInvMsgLen,
+			Reason: "can't be used together with message_payload",
+		}
+	}
+	return nil
+}
+
+func checkOptions(id CommandID, opts *Options) error {
+	if opts == nil {
+		return nil
+	}
+	return opts.Validate(id)
+}
+
+func validateAddrs(srcTon, srcNpi int, src string, dstTon, dstNpi int, dst string) error {
+	return firstError(
+		checkTon(SourceAddrTonFld, srcTon, StatusInvSrcTON),
+		checkNpi(SourceAddrNpiFld, srcNpi, StatusInvSrcNPI),
+		checkCString(SourceAddrFld, src, addrSize, StatusInvSrcAdr),
+		checkTon(DestAddrTonFld, dstTon, StatusInvDstTON),
+		checkNpi(DestAddrNpiFld, dstNpi, StatusInvDstNPI),
+		checkCString(DestinationAddrFld, dst, addrSize, StatusInvDstAdr),
+	)
+}
+
+func validateBind(systemID, password, systemType, addrRange string) error {
+	return firstError(
+		checkCString(SystemIDFld, systemID, systemIDSize, StatusInvSysID),
+		checkCString(PasswordFld, password, passwordSize, StatusInvPaswd),
+		checkCString(SystemTypeFld, systemType, systemTypeSize, StatusInvSysTyp),
+		checkCString(AddressRangeFld, addrRange, addressRangeSize, StatusInvParLen),
+	)
+}
+
+func validateSystemIDResp(id CommandID, systemID string, opts *Options) error {
+	return firstError(
+		checkCString(SystemIDFld, systemID, systemIDSize, StatusInvSysID),
+		checkOptions(id, opts),
+	)
+}
+
+func validateMessageIDResp(id CommandID, msgID string, opts *Options) error {
+	return firstError(
+		checkCString(MessageIDFld, msgID, messageIDSize, StatusInvMsgID),
+		checkOptions(id, opts),
+	)
+}
@@ -0,0 +1,270 @@
+package smpp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// Balance is strategy for distributing requests across sessions of the pool.
+type Balance int
+
+const (
+	// RoundRobin sends requests to bound sessions in turns.
+	RoundRobin Balance = iota
+	// LeastOutstanding sends request to the session with the least requests
+	// waiting for the response.
+	LeastOutstanding
+)
+
+// PoolConf configures Pool.
+type PoolConf struct {
+	// Size is number of binds maintained by the pool. Default is 1.
+	Size int
+	// Addrs the binds are spread across. Default is BindConf.Addr.
+	Addrs []string
+	// Balance strategy, default is RoundRobin.
+	Balance Balance
+	// Bind creates the sessions. Default is BindTRx.
+	Bind func(SessionConf, BindConf) (*Session, error)
+	// ReconnectDelay is time to wait before binding again after failed bind
+	// or closed session. Default is 1 second.
+	ReconnectDelay time.Duration
+	// MaxReconnectDelay limits the delay which doubles after every failed
+	// bind or closed session. The delay is reset once session stays bound
+	// for this long. Default is 1 minute.
+	MaxReconnectDelay time.Duration
+}
+
+// Pool maintains number of binds, possibly to several addresses, and
+// distributes requests across them. Closed sessions are removed from the
+// pool and bound again. Sessions of the pool get SessionConf.ID with the slot
+// number appended.
+type Pool struct {
+	conf PoolConf
+	sc   SessionConf
+	bc   BindConf
+
+	mu       sync.Mutex
+	sessions []*Session
+	next     int
+	closed   bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+var (
+	errPoolEmpty  = Error{Msg: "smpp: no bound session in pool", Temp: true}
+	errPoolClosed = Error{Msg: "smpp: pool closed"}
+)
+
+// NewPool creates pool and starts binding its sessions in background.
+// Pool must be closed after use.
+func NewPool(sc SessionConf, bc BindConf, pc PoolConf) *Pool {
+	if pc.Size < 1 {
+		pc.Size = 1
+	}
+	if len(pc.Addrs) == 0 {
+		pc.Addrs = []string{bc.Addr}
+	}
+	if pc.Bind == nil {
+		pc.Bind = BindTRx
+	}
+	if pc.ReconnectDelay == 0 {
+		pc.ReconnectDelay = time.Second
+	}
+	if pc.MaxReconnectDelay == 0 {
+		pc.MaxReconnectDelay = time.Minute
+	}
+	if pc.MaxReconnectDelay < pc.ReconnectDelay {
+		pc.MaxReconnectDelay = pc.ReconnectDelay
+	}
+	if sc.Logger == nil {
+		sc.Logger = DefaultLogger{}
+	}
+	if sc.ID == "" {
+		sc.ID = genSessionID()
+	}
+	p := &Pool{
+		conf:     pc,
+		sc:       sc,
+		bc:       bc,
+		sessions: make([]*Session, pc.Size),
+		done:     make(chan struct{}),
+	}
+	p.wg.Add(pc.Size)
+	for i := 0; i < pc.Size; i++ {
+		go p.maintain(i, pc.Addrs[i%len(pc.Addrs)])
+	}
+	return p
+}
+
+// maintain keeps session in the slot bound.
+func (p *Pool) maintain(i int, addr string) {
+	defer p.wg.Done()
+	sc := p.sc
+	sc.ID = fmt.Sprintf("%s-%d", p.sc.ID, i)
+	bc := p.bc
+	bc.Addr = addr
+	delay := p.conf.ReconnectDelay
+	for {
+		sess, err := p.conf.Bind(sc, bc)
+		if err != nil {
+			if sess != nil {
+				sess.Close()
+			}
+			p.sc.Logger.ErrorF("pool binding to %s: %+v", addr, err)
+		} else {
+			if !p.set(i, sess) {
+				sess.Close()
+				return
+			}
+			bound := time.Now()
+			select {
+			case <-sess.NotifyClosed():
+				p.set(i, nil)
+			case <-p.done:
+				return
+			}
+			if time.Since(bound) >= p.conf.MaxReconnectDelay {
+				delay = p.conf.ReconnectDelay
+			}
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-p.done:
+			t.Stop()
+			return
+		}
+		if delay *= 2; delay > p.conf.MaxReconnectDelay {
+			delay = p.conf.MaxReconnectDelay
+		}
+	}
+}
+
+// set puts session to the slot, it returns false if the pool is closed.
+func (p *Pool) set(i int, sess *Session) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	p.sessions[i] = sess
+	return true
+}
+
+// Sessions returns currently bound sessions.
+func (p *Pool) Sessions() []*Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var sessions []*Session
+	for _, sess := range p.sessions {
+		if sess != nil {
+			sessions = append(sessions, sess)
+		}
+	}
+	return sessions
+}
+
+// Session returns session for the next request chosen by the balance
+// strategy or nil if there is no bound session.
+func (p *Pool) Session() *Session {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.session()
+}
+
+// Must be guarded by mutex.
+func (p *Pool) session() *Session {
+	n := len(p.sessions)
+	if p.conf.Balance == LeastOutstanding {
+		var (
+			best *Session
+			min  int
+		)
+		for i := 0; i < n; i++ {
+			sess := p.sessions[(p.next+i)%n]
+			if sess == nil {
+				continue
+			}
+			if o := sess.outstanding(); best == nil || o < min {
+				best, min = sess, o
+			}
+		}
+		p.next = (p.next + 1) % n
+		return best
+	}
+	for i := 0; i < n; i++ {
+		sess := p.sessions[p.next]
+		p.next = (p.next + 1) % n
+		if sess != nil {
+			return sess
+		}
+	}
+	return nil
+}
+
+// Send sends PDU using one of the bound sessions, see Session.Send.
+// Temporary error is returned if no session is bound, permanent one if
+// the pool is closed.
+func (p *Pool) Send(ctx context.Context, req pdu.PDU, opts ...pdu.EncoderOption) (pdu.Header, pdu.PDU, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, nil, errPoolClosed
+	}
+	sess := p.session()
+	p.mu.Unlock()
+	if sess == nil {
+		return nil, nil, errPoolEmpty
+	}
+	return sess.Send(ctx, req, opts...)
+}
+
+// Next selects another session of the pool, it can be used as
+// RetryPolicy.Next.
+func (p *Pool) Next(failed *Session, err error) *Session {
+	sess := p.Session()
+	if sess == failed && len(p.Sessions()) > 1 {
+		sess = p.Session()
+	}
+	return sess
+}
+
+// Close stops binding and unbinds all sessions of the pool.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return Error{Msg: "smpp: pool already closed"}
+	}
+	p.closed = true
+	close(p.done)
+	sessions := p.sessions
+	p.sessions = make([]*Session, len(sessions))
+	p.mu.Unlock()
+	timeout := p.sc.WindowTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, sess := range sessions {
+		if sess == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(sess *Session) {
+			defer wg.Done()
+			_ = Unbind(ctx, sess)
+		}(sess)
+	}
+	wg.Wait()
+	p.wg.Wait()
+	return nil
+}
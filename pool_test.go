@@ -0,0 +1,184 @@
+package smpp_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/internal/mock"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func startPoolServer(t *testing.T) (*smpp.Server, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := smpp.NewServer(ln.Addr().String(), smpp.SessionConf{
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransceiverID:
+				btrx, err := ctx.BindTRx()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ctx.Respond(btrx.Response("SMSC"), pdu.StatusOK)
+			case pdu.SubmitSmID:
+				sm, err := ctx.SubmitSm()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if sm.SourceAddr == "noresp" {
+					return
+				}
+				ctx.Respond(sm.Response(ctx.SessionID()), pdu.StatusOK)
+			case pdu.UnbindID:
+				ubd, err := ctx.Unbind()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ctx.Respond(ubd.Response(), pdu.StatusOK)
+			}
+		}),
+	})
+	go srv.Serve(ln)
+	return srv, ln.Addr().String()
+}
+
+func waitPoolSize(t *testing.T, p *smpp.Pool, n int, without ...*smpp.Session) []*smpp.Session {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if sessions := p.Sessions(); len(sessions) == n && !containsSession(sessions, without) {
+			return sessions
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("pool didn't reach %d sessions", n)
+	return nil
+}
+
+func containsSession(sessions, other []*smpp.Session) bool {
+	for _, sess := range sessions {
+		for _, o := range other {
+			if sess == o {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	srv, addr := startPoolServer(t)
+	defer srv.Close()
+	p := smpp.NewPool(smpp.SessionConf{ID: "pool"}, smpp.BindConf{Addr: addr}, smpp.PoolConf{
+		Size:           2,
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	ids := make(map[string]bool)
+	for _, sess := range waitPoolSize(t, p, 2) {
+		ids[sess.ID()] = true
+	}
+	if !ids["pool-0"] || !ids["pool-1"] {
+		t.Errorf("unexpected session ids %v", ids)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	count := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		_, resp, err := p.Send(ctx, &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		count[resp.(*pdu.SubmitSmResp).MessageID]++
+	}
+	if len(count) != 2 {
+		t.Errorf("requests not distributed %v", count)
+	}
+	for id, n := range count {
+		if n != 2 {
+			t.Errorf("session %s got %d requests", id, n)
+		}
+	}
+
+	closed := p.Sessions()[0]
+	closed.Close()
+	waitPoolSize(t, p, 2, closed)
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Send(ctx, pdu.EnquireLink{}); err == nil || smpp.IsTemporary(err) {
+		t.Errorf("expected permanent error from closed pool got %v", err)
+	}
+	if sess := p.Next(nil, nil); sess != nil {
+		t.Errorf("closed pool returned session %s", sess)
+	}
+}
+
+func TestPoolLeastOutstanding(t *testing.T) {
+	srv, addr := startPoolServer(t)
+	defer srv.Close()
+	p := smpp.NewPool(smpp.SessionConf{}, smpp.BindConf{Addr: addr}, smpp.PoolConf{
+		Size:    2,
+		Addrs:   []string{addr, addr},
+		Balance: smpp.LeastOutstanding,
+	})
+	defer p.Close()
+	sessions := waitPoolSize(t, p, 2)
+	// Request without the response keeps the first session busy.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	sm := &pdu.SubmitSm{SourceAddr: "noresp", DestinationAddr: "destination"}
+	err := sessions[0].SendAsync(ctx, sm, func(pdu.Header, pdu.PDU, error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if sess := p.Session(); sess != sessions[1] {
+			t.Errorf("busy session selected")
+		}
+	}
+}
+
+func TestPoolReconnectBackoff(t *testing.T) {
+	binds := make(chan time.Time, 5)
+	n := 0
+	p := smpp.NewPool(smpp.SessionConf{}, smpp.BindConf{}, smpp.PoolConf{
+		Bind: func(sc smpp.SessionConf, bc smpp.BindConf) (*smpp.Session, error) {
+			select {
+			case binds <- time.Now():
+			default:
+			}
+			if n++; n%2 == 0 {
+				return nil, errors.New("bind failed")
+			}
+			// Session closed right after the bind is bound again only after
+			// the delay as well.
+			sess := smpp.NewSession(mock.NewConn().Closed(), sc)
+			sess.Close()
+			return sess, nil
+		},
+		ReconnectDelay:    10 * time.Millisecond,
+		MaxReconnectDelay: 40 * time.Millisecond,
+	})
+	defer p.Close()
+	prev := <-binds
+	for _, min := range []time.Duration{10, 20, 40, 40} {
+		select {
+		case next := <-binds:
+			if d := next.Sub(prev); d < min*time.Millisecond {
+				t.Errorf("bound again after %s expected at least %dms", d, min)
+			}
+			prev = next
+		case <-time.After(time.Second):
+			t.Fatal("pool didn't bind again")
+		}
+	}
+}
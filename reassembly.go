@@ -0,0 +1,273 @@
+package smpp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+const defaultReassemblyTimeout = 5 * time.Minute
+
+type concatKey struct {
+	src string
+	dst string
+	ref int
+	// Wide is set for 16 bit references which are distinct from 8 bit
+	// ones with the same value.
+	wide bool
+}
+
+type concatMsg struct {
+	total   int
+	parts   map[int]*pdu.DeliverSm
+	content map[int][]byte
+	created time.Time
+}
+
+// Reassembler collects parts of concatenated mobile originated messages
+// and joins them once all of the parts are received. Parts can be marked
+// either with UDH concatenation element (8 or 16 bit reference) or with
+// sar_msg_ref_num, sar_total_segments and sar_segment_seqnum options.
+// Parts are matched by source address, destination address, reference
+// number and its width and they can arrive out of order or more than once.
+// Zero value is ready to use.
+type Reassembler struct {
+	// Timeout after which incomplete message is discarded. Default is 5
+	// minutes.
+	Timeout time.Duration
+	// Expired is called with received parts of the message that wasn't
+	// completed before the timeout or whose reference was reused with
+	// different number of parts.
+	Expired func(parts []*pdu.DeliverSm)
+
+	mu   sync.Mutex
+	msgs map[concatKey]*concatMsg
+}
+
+// NewReassembler creates reassembler which discards incomplete messages
+// after timeout.
+func NewReassembler(timeout time.Duration) *Reassembler {
+	if timeout == 0 {
+		timeout = defaultReassemblyTimeout
+	}
+	return &Reassembler{
+		Timeout: timeout,
+	}
+}
+
+// Add stores part of the concatenated message. Once the last missing part
+// is added complete message is returned, until then result is nil.
+// Messages that are not concatenated are returned unchanged.
+//
+// Complete message is the first part with short_message replaced by the
+// joined content. Content longer than 254 bytes is set as message_payload.
+// Concatenation UDH elements and SAR options are removed.
+func (r *Reassembler) Add(sm *pdu.DeliverSm) (*pdu.DeliverSm, error) {
+	if sm == nil {
+		return nil, errors.New("smpp: reassembling nil deliver_sm")
+	}
+	key, total, seq, content, ok, err := concatPart(sm)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || total == 1 {
+		return sm, nil
+	}
+	if total == 0 || seq == 0 || seq > total {
+		return nil, errors.New("smpp: invalid concatenated message part")
+	}
+	now := time.Now()
+	r.mu.Lock()
+	expired := r.expire(now)
+	msg, expired, err := r.add(now, sm, key, total, seq, content, expired)
+	r.mu.Unlock()
+	r.notify(expired)
+	return msg, err
+}
+
+// Must be guarded by mutex.
+func (r *Reassembler) add(now time.Time, sm *pdu.DeliverSm, key concatKey, total, seq int, content []byte, expired [][]*pdu.DeliverSm) (*pdu.DeliverSm, [][]*pdu.DeliverSm, error) {
+	msg, ok := r.msgs[key]
+	if ok && msg.total != total {
+		// Reference was reused before the previous message was completed.
+		expired = append(expired, msg.received())
+		ok = false
+	}
+	if !ok {
+		msg = &concatMsg{
+			total:   total,
+			parts:   make(map[int]*pdu.DeliverSm, total),
+			content: make(map[int][]byte, total),
+			created: now,
+		}
+		if r.msgs == nil {
+			r.msgs = make(map[concatKey]*concatMsg)
+		}
+		r.msgs[key] = msg
+	}
+	if _, dup := msg.parts[seq]; dup {
+		return nil, expired, nil
+	}
+	msg.parts[seq] = sm
+	msg.content[seq] = content
+	if len(msg.parts) < msg.total {
+		return nil, expired, nil
+	}
+	delete(r.msgs, key)
+	joined, err := msg.join()
+	return joined, expired, err
+}
+
+// Len returns number of incomplete messages waiting for their parts.
+func (r *Reassembler) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.msgs)
+}
+
+// ExpireNow discards messages incomplete at the time and reports them to
+// Expired. Expiry is otherwise checked only when a part is added so it
+// should be called periodically if the traffic can stop.
+func (r *Reassembler) ExpireNow(now time.Time) {
+	r.mu.Lock()
+	expired := r.expire(now)
+	r.mu.Unlock()
+	r.notify(expired)
+}
+
+// Must be guarded by mutex.
+func (r *Reassembler) expire(now time.Time) [][]*pdu.DeliverSm {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = defaultReassemblyTimeout
+	}
+	var expired [][]*pdu.DeliverSm
+	for key, msg := range r.msgs {
+		if now.Sub(msg.created) < timeout {
+			continue
+		}
+		delete(r.msgs, key)
+		expired = append(expired, msg.received())
+	}
+	return expired
+}
+
+func (r *Reassembler) notify(expired [][]*pdu.DeliverSm) {
+	if r.Expired == nil {
+		return
+	}
+	for _, parts := range expired {
+		r.Expired(parts)
+	}
+}
+
+// received returns parts of the message in order.
+func (msg *concatMsg) received() []*pdu.DeliverSm {
+	parts := make([]*pdu.DeliverSm, 0, len(msg.parts))
+	for i := 1; i <= msg.total; i++ {
+		if p, ok := msg.parts[i]; ok {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+func (msg *concatMsg) join() (*pdu.DeliverSm, error) {
+	var content []byte
+	for i := 1; i <= msg.total; i++ {
+		content = append(content, msg.content[i]...)
+	}
+	first := *msg.parts[1]
+	var udh pdu.UDH
+	if first.EsmClass.Feature&pdu.UDHIEsmFeat != 0 {
+		var err error
+		if udh, _, err = pdu.ParseUDH(message(&first)); err != nil {
+			return nil, err
+		}
+	}
+	if first.Options != nil {
+		first.Options = first.Options.Clone().Delete(pdu.TagSarMsgRefNum,
+			pdu.TagSarTotalSegments, pdu.TagSarSegmentSeqnum, pdu.TagMessagePayload)
+	}
+	if err := first.SetUDH(*udh.Delete(pdu.IEConcat8, pdu.IEConcat16), content); err != nil {
+		return nil, err
+	}
+	if len(first.ShortMessage) > 254 {
+		if first.Options == nil {
+			first.Options = pdu.NewOptions()
+		}
+		first.Options.Set(pdu.TagMessagePayload, first.ShortMessage)
+		first.ShortMessage = nil
+	}
+	return &first, nil
+}
+
+// ReassembleDeliverSm wraps handler so it receives only complete deliver_sm
+// messages. Parts of incomplete messages are acknowledged with deliver_sm_resp
+// and the handler is called once the message is complete.
+// All other requests are passed to the handler unchanged.
+func ReassembleDeliverSm(r *Reassembler, h Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		if ctx.CommandID() != pdu.DeliverSmID {
+			h.ServeSMPP(ctx)
+			return
+		}
+		sm, err := ctx.DeliverSm()
+		if err != nil {
+			h.ServeSMPP(ctx)
+			return
+		}
+		msg, err := r.Add(sm)
+		if err != nil {
+			ctx.Sess.conf.Logger.ErrorF("reassembling deliver_sm: %s %+v", ctx.Sess, err)
+			h.ServeSMPP(ctx)
+			return
+		}
+		if msg == nil {
+			if err := ctx.Respond(sm.Response(""), pdu.StatusOK); err != nil {
+				ctx.Sess.conf.Logger.ErrorF("acknowledging deliver_sm part: %s %+v", ctx.Sess, err)
+			}
+			return
+		}
+		ctx.req = msg
+		h.ServeSMPP(ctx)
+	})
+}
+
+// message returns raw message bytes from short_message or message_payload.
+func message(sm *pdu.DeliverSm) []byte {
+	if len(sm.ShortMessage) == 0 && sm.Options != nil {
+		if b, ok := sm.Options.Get(pdu.TagMessagePayload); ok {
+			return b
+		}
+	}
+	return sm.ShortMessage
+}
+
+// concatPart extracts concatenation reference, total number of parts and
+// part sequence from UDH or SAR options. Returned content has the UDH removed.
+func concatPart(sm *pdu.DeliverSm) (key concatKey, total, seq int, content []byte, ok bool, err error) {
+	key = concatKey{src: sm.SourceAddr, dst: sm.DestinationAddr}
+	content = message(sm)
+	if sm.EsmClass.Feature&pdu.UDHIEsmFeat != 0 {
+		var udh pdu.UDH
+		udh, content, err = pdu.ParseUDH(content)
+		if err != nil {
+			return key, 0, 0, nil, false, err
+		}
+		if c, ok := udh.Concat(); ok {
+			key.ref, key.wide = c.Ref, c.Wide
+			return key, c.Total, c.Seq, content, true, nil
+		}
+	}
+	if sm.Options != nil {
+		if _, ok := sm.Options.Get(pdu.TagSarMsgRefNum); ok {
+			// sar_msg_ref_num is 16 bit integer.
+			key.ref, key.wide = sm.Options.SarMsgRefNum(), true
+			return key, sm.Options.SarTotalSegments(), sm.Options.SarSegmentSeqnum(), content, true, nil
+		}
+	}
+	return key, 0, 0, content, false, nil
+}
@@ -0,0 +1,138 @@
+package smpp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func udhPart(udh string, content string) *pdu.DeliverSm {
+	return &pdu.DeliverSm{
+		SourceAddr:      "111",
+		DestinationAddr: "222",
+		EsmClass:        pdu.EsmClass{Feature: pdu.UDHIEsmFeat},
+		ShortMessage:    append([]byte(udh), content...),
+	}
+}
+
+func sarPart(ref, total, seq int, content string) *pdu.DeliverSm {
+	return &pdu.DeliverSm{
+		SourceAddr:      "111",
+		DestinationAddr: "222",
+		ShortMessage:    []byte(content),
+		Options: pdu.NewOptions().
+			SetSarMsgRefNum(ref).
+			SetSarTotalSegments(total).
+			SetSarSegmentSeqnum(seq),
+	}
+}
+
+func TestReassemblerUDH(t *testing.T) {
+	// Zero value is usable.
+	r := &smpp.Reassembler{}
+	parts := []*pdu.DeliverSm{
+		udhPart("\x05\x00\x03\xAA\x03\x02", "lo wo"),
+		udhPart("\x05\x00\x03\xAA\x03\x01", "hel"),
+		udhPart("\x05\x00\x03\xAA\x03\x02", "lo wo"),
+		// 16 bit reference with the same value belongs to other message.
+		udhPart("\x06\x08\x04\x00\xAA\x03\x03", "rld"),
+		udhPart("\x05\x00\x03\xAA\x03\x03", "rld"),
+	}
+	for i, p := range parts[:4] {
+		msg, err := r.Add(p)
+		if err != nil {
+			t.Fatalf("part %d: %v", i, err)
+		}
+		if msg != nil {
+			t.Fatalf("part %d: unexpected complete message %s", i, msg.ShortMessage)
+		}
+	}
+	msg, err := r.Add(parts[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg == nil {
+		t.Fatal("expected complete message")
+	}
+	if string(msg.ShortMessage) != "hello world" {
+		t.Errorf("reassembled %q expected %q", msg.ShortMessage, "hello world")
+	}
+	if msg.EsmClass.Feature != pdu.NoEsmFeat {
+		t.Errorf("expected UDHI to be cleared got %d", msg.EsmClass.Feature)
+	}
+	if r.Len() != 1 {
+		t.Errorf("expected one pending 16 bit message got %d", r.Len())
+	}
+}
+
+func TestReassemblerSAR(t *testing.T) {
+	r := smpp.NewReassembler(time.Minute)
+	if msg, _ := r.Add(sarPart(7, 2, 2, "world")); msg != nil {
+		t.Fatal("unexpected complete message")
+	}
+	msg, err := r.Add(sarPart(7, 2, 1, "hello "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg == nil || string(msg.ShortMessage) != "hello world" {
+		t.Fatalf("unexpected reassembled message %+v", msg)
+	}
+	if msg.Options.Len() != 0 {
+		t.Errorf("expected sar options to be removed got %s", msg.Options)
+	}
+	single := &pdu.DeliverSm{ShortMessage: []byte("single")}
+	if msg, _ := r.Add(single); msg != single {
+		t.Errorf("expected unconcatenated message to be returned as is")
+	}
+}
+
+func TestReassemblerExpire(t *testing.T) {
+	r := smpp.NewReassembler(10 * time.Millisecond)
+	var expired []*pdu.DeliverSm
+	r.Expired = func(parts []*pdu.DeliverSm) {
+		expired = append(expired, parts...)
+	}
+	r.Add(sarPart(1, 2, 1, "first"))
+	time.Sleep(20 * time.Millisecond)
+	if msg, _ := r.Add(sarPart(1, 2, 2, "second")); msg != nil {
+		t.Errorf("expected expired message not to be completed")
+	}
+	if len(expired) != 1 || string(expired[0].ShortMessage) != "first" {
+		t.Errorf("expected first part to expire got %+v", expired)
+	}
+}
+
+func TestReassemblerExpireNow(t *testing.T) {
+	r := smpp.NewReassembler(time.Minute)
+	var expired [][]*pdu.DeliverSm
+	r.Expired = func(parts []*pdu.DeliverSm) {
+		expired = append(expired, parts)
+	}
+	r.Add(sarPart(1, 2, 1, "first"))
+	r.ExpireNow(time.Now())
+	if len(expired) != 0 {
+		t.Errorf("message expired before the timeout")
+	}
+	r.ExpireNow(time.Now().Add(time.Minute))
+	if len(expired) != 1 || r.Len() != 0 {
+		t.Errorf("expected incomplete message to expire got %+v", expired)
+	}
+}
+
+func TestReassemblerReusedRef(t *testing.T) {
+	r := smpp.NewReassembler(time.Minute)
+	var expired [][]*pdu.DeliverSm
+	r.Expired = func(parts []*pdu.DeliverSm) {
+		expired = append(expired, parts)
+	}
+	r.Add(sarPart(1, 2, 1, "old"))
+	r.Add(sarPart(1, 3, 1, "new"))
+	if len(expired) != 1 || string(expired[0][0].ShortMessage) != "old" {
+		t.Errorf("expected replaced message to be reported got %+v", expired)
+	}
+	if r.Len() != 1 {
+		t.Errorf("expected new message to wait for parts")
+	}
+}
@@ -0,0 +1,126 @@
+package smpp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// IsTemporary reports whether the request failed with error after which it
+// can be sent again. These are responses with StatusThrottled, StatusMsgQFul
+// and StatusSysErr, errors whose Temporary method returns true and errors
+// caused by the session being closed, also when wrapped. Note that after
+// SessionClosedBeforeReceiving the request may have been accepted by the peer.
+func IsTemporary(err error) bool {
+	var se StatusError
+	if errors.As(err, &se) {
+		switch se.Status() {
+		case pdu.StatusThrottled, pdu.StatusMsgQFul, pdu.StatusSysErr:
+			return true
+		}
+		return false
+	}
+	var te interface{ Temporary() bool }
+	if errors.As(err, &te) {
+		return te.Temporary() || errors.Is(err, errWriterClosed)
+	}
+	return errors.Is(err, SessionClosedBeforeReceiving)
+}
+
+// RetryPolicy configures resending of requests failed with temporary error.
+type RetryPolicy struct {
+	// MaxAttempts including the first one. Default is 3.
+	MaxAttempts int
+	// MinBackoff is delay before the first retry. Default is 100 milliseconds.
+	MinBackoff time.Duration
+	// MaxBackoff limits the delay between retries. Default is 10 seconds.
+	MaxBackoff time.Duration
+	// Multiplier increases the delay after each retry. Default is 2.
+	Multiplier float64
+	// Retryable reports whether request failed with the error should be
+	// retried. Default is IsTemporary.
+	Retryable func(err error) bool
+	// Next returns session used for the retry after the request failed on
+	// the session. If nil or it returns nil the same session is used.
+	Next func(sess *Session, err error) *Session
+}
+
+var userMessageRef uint32
+
+// nextUserMessageRef returns user_message_reference for requests that
+// don't have one. Zero is skipped as it can't be told apart from missing
+// reference.
+func nextUserMessageRef() int {
+	for {
+		if ref := uint16(atomic.AddUint32(&userMessageRef, 1)); ref != 0 {
+			return int(ref)
+		}
+	}
+}
+
+func (rp RetryPolicy) retryable(err error) bool {
+	if rp.Retryable != nil {
+		return rp.Retryable(err)
+	}
+	return IsTemporary(err)
+}
+
+func (rp RetryPolicy) backoff(retry int) time.Duration {
+	min, max, mul := rp.MinBackoff, rp.MaxBackoff, rp.Multiplier
+	if min <= 0 {
+		min = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	if mul < 1 {
+		mul = 2
+	}
+	d := float64(min)
+	for i := 1; i < retry && d < float64(max); i++ {
+		d *= mul
+	}
+	if d > float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// SendSubmitSm sends SubmitSm PDU retrying it according to the policy.
+// Every attempt carries the same user_message_reference so the peer or
+// the receipts can reveal duplicates. If the PDU has no such option it is
+// set on the PDU before the first attempt. Error of the last attempt is
+// returned.
+func (rp RetryPolicy) SendSubmitSm(ctx context.Context, sess *Session, p *pdu.SubmitSm) (*pdu.SubmitSmResp, error) {
+	if p.Options == nil {
+		p.Options = pdu.NewOptions()
+	}
+	if _, ok := p.Options.GetDouble(pdu.TagUserMessageReference); !ok {
+		p.Options.SetUserMessageReference(nextUserMessageRef())
+	}
+	attempts := rp.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	for i := 1; ; i++ {
+		resp, err := SendSubmitSm(ctx, sess, p)
+		if err == nil || i >= attempts || !rp.retryable(err) {
+			return resp, err
+		}
+		t := time.NewTimer(rp.backoff(i))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		}
+		if rp.Next != nil {
+			if next := rp.Next(sess, err); next != nil {
+				sess = next
+			}
+		}
+	}
+}
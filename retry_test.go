@@ -0,0 +1,166 @@
+package smpp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/internal/mock"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func TestIsTemporary(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		temp bool
+	}{
+		{smpp.SessionClosedBeforeReceiving, true},
+		{fmt.Errorf("submit: %w", smpp.SessionClosedBeforeReceiving), true},
+		{fmt.Errorf("submit: %w", smpp.Error{Msg: "window closed", Temp: true}), true},
+		{smpp.Error{Msg: "window closed", Temp: true}, true},
+		{smpp.Error{Msg: "nil pdu"}, false},
+		{context.Canceled, false},
+		{errors.New("other"), false},
+	} {
+		if temp := smpp.IsTemporary(tc.err); temp != tc.temp {
+			t.Errorf("IsTemporary(%v) = %t", tc.err, temp)
+		}
+	}
+}
+
+func TestRetryPolicySendSubmitSm(t *testing.T) {
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	submitSm.Options = pdu.NewOptions().SetUserMessageReference(7)
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteWrite(e.i(&pdu.BindTRx{})).ByteRead(e.s(&pdu.BindTRxResp{})).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response(""), pdu.StatusThrottled)).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response(""), pdu.StatusMsgQFul)).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id"))).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response(""), pdu.StatusInvDstAdr)).
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, &pdu.BindTRx{}); err != nil {
+		t.Fatal(err)
+	}
+	rp := smpp.RetryPolicy{MinBackoff: time.Millisecond}
+	resp, err := rp.SendSubmitSm(ctx, sess, submitSm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MessageID != "id" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	_, err = rp.SendSubmitSm(ctx, sess, submitSm)
+	if serr, ok := err.(smpp.StatusError); !ok || serr.Status() != pdu.StatusInvDstAdr {
+		t.Errorf("expected permanent error without retry got %v", err)
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestSendSubmitSmGenericNack(t *testing.T) {
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	submitSm.Options = pdu.NewOptions().SetUserMessageReference(7)
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteWrite(e.i(&pdu.BindTRx{})).ByteRead(e.s(&pdu.BindTRxResp{})).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(&pdu.GenericNack{}, pdu.StatusThrottled)).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(&pdu.GenericNack{}, pdu.StatusThrottled)).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id"))).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(&pdu.GenericNack{})).
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, &pdu.BindTRx{}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := smpp.SendSubmitSm(ctx, sess, submitSm)
+	if resp != nil {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if serr, ok := err.(smpp.StatusError); !ok || serr.Status() != pdu.StatusThrottled || !smpp.IsTemporary(err) {
+		t.Errorf("expected temporary throttled status got %v", err)
+	}
+	rp := smpp.RetryPolicy{MinBackoff: time.Millisecond}
+	resp, err = rp.SendSubmitSm(ctx, sess, submitSm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MessageID != "id" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if _, err := smpp.SendSubmitSm(ctx, sess, submitSm); err == nil {
+		t.Error("expected error for generic_nack without status")
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestRetryPolicyNextSession(t *testing.T) {
+	closed := smpp.NewSession(mock.NewConn().Closed(), smpp.SessionConf{})
+	if err := closed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	var ref int
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteWrite(e.i(&pdu.BindTRx{})).ByteRead(e.s(&pdu.BindTRxResp{})).
+		// Retried submit_sm must keep the reference of the first attempt.
+		ProcessWrite(func(step, count int) ([]byte, error) {
+			return newTestEncoder(1).i(&pdu.SubmitSm{
+				SourceAddr:      "source",
+				DestinationAddr: "destination",
+				Options:         pdu.NewOptions().SetUserMessageReference(ref),
+			}), nil
+		}).ByteRead(newTestEncoder(1).i(submitSm.Response("id"))).
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, &pdu.BindTRx{}); err != nil {
+		t.Fatal(err)
+	}
+	rp := smpp.RetryPolicy{
+		MaxAttempts: 2,
+		MinBackoff:  time.Millisecond,
+		Next: func(s *smpp.Session, err error) *smpp.Session {
+			if s != closed {
+				t.Errorf("unexpected failed session %s", s)
+			}
+			ref = submitSm.Options.UserMessageReference()
+			return sess
+		},
+	}
+	resp, err := rp.SendSubmitSm(ctx, closed, submitSm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.MessageID != "id" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if ref == 0 || submitSm.Options.UserMessageReference() != ref {
+		t.Errorf("user_message_reference changed between attempts")
+	}
+	if err := sess.Close(); err != nil {
+		t.Error(err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
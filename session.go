@@ -5,6 +5,7 @@ package smpp
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -149,6 +150,39 @@ type SessionConf struct {
 	Logger        Logger
 	Handler       Handler
 	Sequencer     pdu.Sequencer
+	// WindowWait makes Send wait for a free slot in the sending window
+	// instead of returning temporary error when the window is full.
+	// Waiting senders get slots in FIFO order, except bind, unbind,
+	// enquire_link and generic_nack requests which are served first.
+	WindowWait bool
+	// Limiter limits rate of requests sent by the session. Send waits
+	// for the limiter before taking the slot in the sending window.
+	// FeedbackLimiter observes statuses of all received responses.
+	Limiter Limiter
+	// RequestLimit limits rate of requests received by the session.
+	// Requests over the limit are responded with StatusThrottled.
+	RequestLimit RequestLimit
+	// SystemLimiter limits rate of requests received by all sessions
+	// bound with the same system_id. It's usually shared by the sessions
+	// of the server.
+	SystemLimiter *SystemLimiter
+	// ValidateRequests enables validation of received requests before they
+	// are passed to the handler. Invalid requests are responded with the
+	// status of the validation error.
+	ValidateRequests bool
+	// WriteTimeout limits how long writing PDUs to the connection may take
+	// if the connection supports write deadlines. Default is 10 seconds.
+	WriteTimeout time.Duration
+	// CloseTimeout limits how long Close waits for queued PDUs to be
+	// written before the connection is closed. Default is 1 second.
+	CloseTimeout time.Duration
+	// MaxPDULength limits command_length of received PDUs. Longer PDUs are
+	// discarded and responded with generic_nack. Default is pdu.MaxPDUSize.
+	MaxPDULength uint32
+	// LateResponse is called with responses received after the context of
+	// the request was done. It's called from the goroutine reading the
+	// connection so it shouldn't block.
+	LateResponse func(hdr pdu.Header, resp pdu.PDU)
 	// MapResetInterval specifies the duration after which the session's map will be recreated
 	// to mitigate potential memory growth. Setting this to a positive duration can help
 	// manage memory usage, especially when large amounts of data are added and removed from the map.
@@ -161,6 +195,13 @@ type response struct {
 	err  error
 }
 
+// pending is request in the sending window waiting for the response.
+type pending struct {
+	id pdu.CommandID
+	// done is called once with the response or the error.
+	done func(response)
+}
+
 // Session is the engine that coordinates SMPP protocol for bounded peers.
 type Session struct {
 	conf     *SessionConf
@@ -171,10 +212,21 @@ type Session struct {
 	mu       sync.Mutex
 	seq      uint32
 	reqCount int
-	sent     map[uint32]chan response
+	sent     map[uint32]*pending
+	reqRate  *TokenBucket
+	granted  int
+	waiters  [numLanes][]*waiter
+	expired  map[uint32]time.Time
 	state    SessionState
 	systemID string
 	closed   chan struct{}
+
+	wmu     sync.Mutex
+	queue   []*outFrame
+	werr    error
+	wake    chan struct{}
+	stop    chan struct{}
+	flushed chan struct{}
 }
 
 // NewSession creates new SMPP session and starts goroutine for listening incoming
@@ -195,6 +247,12 @@ func NewSession(rwc io.ReadWriteCloser, conf SessionConf) *Session {
 	if conf.WindowTimeout == 0 {
 		conf.WindowTimeout = 10 * time.Second
 	}
+	if conf.WriteTimeout == 0 {
+		conf.WriteTimeout = 10 * time.Second
+	}
+	if conf.CloseTimeout == 0 {
+		conf.CloseTimeout = time.Second
+	}
 	if conf.ReqWinSize == 0 {
 		conf.ReqWinSize = 10
 	}
@@ -202,18 +260,27 @@ func NewSession(rwc io.ReadWriteCloser, conf SessionConf) *Session {
 		conf.ID = genSessionID()
 	}
 
+	if conf.MaxPDULength == 0 {
+		conf.MaxPDULength = pdu.MaxPDUSize
+	}
 	if conf.MapResetInterval == 0 {
 		conf.MapResetInterval = time.Hour * 12
 	}
 	sess := &Session{
-		conf:   &conf,
-		RWC:    rwc,
-		enc:    pdu.NewEncoder(rwc, conf.Sequencer),
-		dec:    pdu.NewDecoder(rwc),
-		sent:   make(map[uint32]chan response, conf.SendWinSize),
-		closed: make(chan struct{}),
-	}
-	sess.wg.Add(1)
+		conf:    &conf,
+		RWC:     rwc,
+		dec:     pdu.NewDecoder(rwc, pdu.DecodeMaxLength(conf.MaxPDULength)),
+		sent:    make(map[uint32]*pending, conf.SendWinSize),
+		expired: make(map[uint32]time.Time),
+		reqRate: conf.RequestLimit.bucket(),
+		closed:  make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		flushed: make(chan struct{}),
+	}
+	sess.enc = pdu.NewEncoder(nil, conf.Sequencer)
+	sess.wg.Add(2)
+	go sess.writeLoop()
 	go sess.serve()
 	return sess
 }
@@ -255,6 +322,21 @@ func (sess *Session) serve() {
 	go sess.resetSentMapPeriodically(ctx)
 	for {
 		h, p, err := sess.dec.Decode()
+		var bodyErr pdu.BodyError
+		if errors.As(err, &bodyErr) {
+			sess.conf.Logger.ErrorF("decoding pdu body: %s %+v", sess, err)
+			sess.handleInvalid(h, p, bodyErr)
+			continue
+		}
+		var lenErr pdu.LengthError
+		if errors.As(err, &lenErr) {
+			sess.conf.Logger.ErrorF("decoding pdu: %s %+v", sess, err)
+			sess.mu.Lock()
+			sess.reject(h, pdu.StatusInvCmdLen)
+			sess.mu.Unlock()
+			sess.shutdown()
+			return
+		}
 		if err != nil {
 			if err == io.EOF {
 				sess.conf.Logger.DebugF("decoding pdu: %s %+v", sess, err)
@@ -265,7 +347,14 @@ func (sess *Session) serve() {
 			return
 		}
 		sess.mu.Lock()
-		sess.systemID = pdu.SystemID(p)
+		if err := sess.validate(h, p); err != nil {
+			sess.conf.Logger.ErrorF("validating request: %s %+v", sess, err)
+			sess.mu.Unlock()
+			continue
+		}
+		if id := pdu.SystemID(p); id != "" {
+			sess.systemID = id
+		}
 		if err := sess.makeTransition(h.CommandID(), true); err != nil {
 			sess.conf.Logger.ErrorF("transitioning upon receive: %s %+v", sess, err)
 			sess.mu.Unlock()
@@ -274,8 +363,12 @@ func (sess *Session) serve() {
 		// Handle PDU requests.
 		if pdu.IsRequest(h.CommandID()) {
 			sess.conf.Logger.DebugF("received request: %s %s%+v", sess, p.CommandID(), p)
-			if sess.reqCount == sess.conf.ReqWinSize {
-				sess.throttle(h.Sequence())
+			if !sess.allow(h.CommandID()) {
+				sess.conf.Logger.InfoF("request over rate limit: %s %s", sess, h.CommandID())
+				sess.reject(h, pdu.StatusThrottled)
+			} else if sess.reqCount == sess.conf.ReqWinSize {
+				sess.conf.Logger.InfoF("request window full: %s %s", sess, h.CommandID())
+				sess.reject(h, pdu.StatusThrottled)
 			} else {
 				sess.wg.Add(1)
 				sess.reqCount++
@@ -285,15 +378,33 @@ func (sess *Session) serve() {
 			continue
 		}
 		// Handle PDU responses.
-		if l, ok := sess.sent[h.Sequence()]; ok {
+		if req, ok := sess.sent[h.Sequence()]; ok {
 			sess.conf.Logger.DebugF("received response: %s %s%+v", sess, p.CommandID(), p)
-			delete(sess.sent, h.Sequence())
+			sess.release(h.Sequence())
 			sess.mu.Unlock()
 
-			l <- response{
+			if fl, ok := sess.conf.Limiter.(FeedbackLimiter); ok {
+				fl.Observe(req.id, h.Status())
+			}
+			err := toError(h.Status())
+			if err == nil && h.CommandID() == pdu.GenericNackID {
+				// generic_nack always rejects the request even if the peer
+				// didn't set error status.
+				err = toError(pdu.StatusUnknownErr)
+			}
+			req.done(response{
 				hdr:  h,
 				resp: p,
-				err:  toError(h.Status()),
+				err:  err,
+			})
+			continue
+		}
+		if _, ok := sess.expired[h.Sequence()]; ok {
+			sess.conf.Logger.DebugF("received late response: %s %s%+v", sess, p.CommandID(), p)
+			delete(sess.expired, h.Sequence())
+			sess.mu.Unlock()
+			if late := sess.conf.LateResponse; late != nil {
+				late(h, p)
 			}
 			continue
 		}
@@ -302,12 +413,83 @@ func (sess *Session) serve() {
 	}
 }
 
-func (sess *Session) throttle(seq uint32) {
-	resp := pdu.GenericNack{}
-	if _, err := sess.enc.Encode(resp, pdu.EncodeStatus(pdu.StatusThrottled), pdu.EncodeSeq(seq)); err != nil {
-		sess.conf.Logger.ErrorF("error encoding pdu: %s %+v", sess, err)
+// handleInvalid responds to the request with undecodable body or passes
+// the error to the sender if the response couldn't be decoded.
+func (sess *Session) handleInvalid(h pdu.Header, p pdu.PDU, err pdu.BodyError) {
+	sess.mu.Lock()
+	if pdu.IsRequest(h.CommandID()) {
+		sess.reject(h, err.Status)
+		sess.mu.Unlock()
 		return
 	}
+	req, ok := sess.sent[h.Sequence()]
+	if !ok {
+		sess.mu.Unlock()
+		return
+	}
+	sess.release(h.Sequence())
+	sess.mu.Unlock()
+	req.done(response{
+		hdr:  h,
+		resp: p,
+		err:  err,
+	})
+}
+
+// validate rejects invalid request if validation of requests is enabled.
+//
+// Must be guarded by mutex.
+func (sess *Session) validate(h pdu.Header, p pdu.PDU) error {
+	if !sess.conf.ValidateRequests || !pdu.IsRequest(h.CommandID()) {
+		return nil
+	}
+	v, ok := p.(pdu.Validator)
+	if !ok {
+		return nil
+	}
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+	status, ok := pdu.ErrorStatus(err)
+	if !ok {
+		status = pdu.StatusSysErr
+	}
+	sess.reject(h, status)
+	return err
+}
+
+// reject responds to the request with error status. Requests with invalid
+// length or without response are rejected with generic_nack.
+//
+// Must be guarded by mutex.
+func (sess *Session) reject(h pdu.Header, status pdu.Status) {
+	resp, ok := pdu.NewResponse(h.CommandID())
+	if !ok || status == pdu.StatusInvCmdLen {
+		resp = pdu.GenericNack{}
+	}
+	opts := []pdu.EncoderOption{pdu.EncodeStatus(status), pdu.EncodeSeq(h.Sequence())}
+	_, _, err := sess.enqueue(resp, opts...)
+	if err != nil && resp.CommandID() != pdu.GenericNackID {
+		_, _, err = sess.enqueue(pdu.GenericNack{}, opts...)
+	}
+	if err != nil {
+		sess.conf.Logger.ErrorF("error encoding pdu: %s %+v", sess, err)
+	}
+}
+
+// allow checks received request against the rate limits. System limiter
+// is keyed by system_id the peer bound with.
+//
+// Must be guarded by mutex.
+func (sess *Session) allow(id pdu.CommandID) bool {
+	if sess.reqRate != nil && sess.conf.RequestLimit.limited(id) && !sess.reqRate.Allow() {
+		return false
+	}
+	if sl := sess.conf.SystemLimiter; sl != nil && !sl.Allow(sess.systemID, id) {
+		return false
+	}
+	return true
 }
 
 func (sess *Session) handleRequest(ctx context.Context, h pdu.Header, req pdu.PDU) {
@@ -345,16 +527,30 @@ func (sess *Session) Close() error {
 		sess.mu.Unlock()
 		return err
 	}
-	for k, l := range sess.sent {
+	sent := make([]*pending, 0, len(sess.sent))
+	for k, req := range sess.sent {
 		delete(sess.sent, k)
-		close(l)
+		sent = append(sent, req)
 	}
-	sess.RWC.Close()
+	sess.failWaiters(errWriterClosed)
+	close(sess.stop)
 	if err := sess.setState(StateClosed); err != nil {
 		sess.mu.Unlock()
 		return err
 	}
 	sess.mu.Unlock()
+	// Connection is closed once the queued PDUs are written or when the
+	// peer doesn't read them in time, which also unblocks the writer.
+	t := time.NewTimer(sess.conf.CloseTimeout)
+	select {
+	case <-sess.flushed:
+	case <-t.C:
+	}
+	t.Stop()
+	sess.RWC.Close()
+	for _, req := range sent {
+		req.done(response{err: SessionClosedBeforeReceiving})
+	}
 	sess.wg.Wait()
 	sess.conf.Logger.DebugF("session closed: %s", sess)
 	close(sess.closed)
@@ -373,7 +569,7 @@ func (sess *Session) setState(state SessionState) error {
 		}
 	case StateBinding:
 		switch state {
-		case StateOpen, StateBoundRx, StateBoundTRx, StateBoundTx:
+		case StateOpen, StateBoundRx, StateBoundTRx, StateBoundTx, StateClosing:
 		default:
 			return fmt.Errorf("smpp: setting binding session to invalid state %s", state)
 		}
@@ -403,40 +599,129 @@ func (sess *Session) setState(state SessionState) error {
 
 // Send writes PDU to the bounded connection effectively sending it to the peer.
 // Use context deadline to specify how much you would like to wait for the response.
+// Once the context is done the request is removed from the sending window and
+// its response is passed to SessionConf.LateResponse if it arrives later.
+// If writing the PDU fails the write error is returned.
+// When the sending window is full temporary error is returned unless
+// SessionConf.WindowWait is set in which case Send waits for a free slot.
+// Send also waits for SessionConf.Limiter if the rate of requests is limited.
 func (sess *Session) Send(ctx context.Context, req pdu.PDU, opts ...pdu.EncoderOption) (pdu.Header, pdu.PDU, error) {
+	l := make(chan response, 1)
+	p := &pending{done: func(resp response) {
+		l <- resp
+	}}
+	seq, err := sess.send(ctx, req, p, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	select {
+	case resp := <-l:
+		return resp.hdr, resp.resp, resp.err
+	case <-ctx.Done():
+		if !sess.expire(seq, p) {
+			// Response was received in the meantime.
+			resp := <-l
+			return resp.hdr, resp.resp, resp.err
+		}
+		return nil, nil, ctx.Err()
+	}
+}
+
+// SendAsync writes PDU to the bounded connection and returns without waiting
+// for the response. Callback is called once with the response or the error,
+// including the error of the context. It's called from the goroutine reading
+// the connection so it shouldn't block.
+// Error is returned if the request couldn't be queued for sending in which
+// case the callback isn't called. With SessionConf.WindowWait set SendAsync
+// blocks until there is a free slot in the sending window.
+func (sess *Session) SendAsync(ctx context.Context, req pdu.PDU, cb func(pdu.Header, pdu.PDU, error), opts ...pdu.EncoderOption) error {
+	var finished chan struct{}
+	if ctx.Done() != nil {
+		finished = make(chan struct{})
+	}
+	p := &pending{done: func(resp response) {
+		if finished != nil {
+			close(finished)
+		}
+		cb(resp.hdr, resp.resp, resp.err)
+	}}
+	seq, err := sess.send(ctx, req, p, opts...)
+	if err != nil {
+		return err
+	}
+	if finished != nil {
+		go func() {
+			select {
+			case <-finished:
+			case <-ctx.Done():
+				if sess.expire(seq, p) {
+					p.done(response{err: ctx.Err()})
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// send queues request for writing and registers it in the sending window.
+func (sess *Session) send(ctx context.Context, req pdu.PDU, p *pending, opts ...pdu.EncoderOption) (uint32, error) {
 	if req == nil {
-		return nil, nil, Error{Msg: "smpp: sending nil pdu"}
+		return 0, Error{Msg: "smpp: sending nil pdu"}
+	}
+	if sess.conf.Limiter != nil {
+		if err := sess.conf.Limiter.Wait(ctx, req.CommandID()); err != nil {
+			return 0, err
+		}
 	}
 	sess.mu.Lock()
-	if len(sess.sent) == sess.conf.SendWinSize {
-		sess.mu.Unlock()
-		return nil, nil, Error{Msg: "smpp: sending window closed", Temp: true}
+	defer sess.mu.Unlock()
+	if err := sess.acquire(ctx, req.CommandID()); err != nil {
+		return 0, err
 	}
 	if err := sess.makeTransition(req.CommandID(), false); err != nil {
 		sess.conf.Logger.ErrorF("transitioning before send: %s %+v", sess, err)
-		sess.mu.Unlock()
-		return nil, nil, err
+		sess.grant()
+		return 0, err
 	}
-	seq, err := sess.enc.Encode(req, opts...)
+	seq, f, err := sess.encode(req, opts...)
 	if err != nil {
-		sess.mu.Unlock()
-		return nil, nil, err
+		sess.grant()
+		return 0, err
 	}
-	l := make(chan response, 1)
-	sess.sent[seq] = l
+	f.request = true
+	if err := sess.push(f); err != nil {
+		sess.grant()
+		return 0, err
+	}
+	p.id = req.CommandID()
+	sess.sent[seq] = p
 	sess.conf.Logger.DebugF("request sent: %s %s%+v", sess, req.CommandID(), req)
+	return seq, nil
+}
+
+// expire removes request from the sending window if it's still waiting
+// for the response and remembers its sequence to recognize late response.
+func (sess *Session) expire(seq uint32, p *pending) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.sent[seq] != p {
+		return false
+	}
+	sess.release(seq)
+	sess.expired[seq] = time.Now()
+	return true
+}
+
+// failSent fails request waiting for the response with the error.
+func (sess *Session) failSent(seq uint32, err error) {
+	sess.mu.Lock()
+	req, ok := sess.sent[seq]
+	if ok {
+		sess.release(seq)
+	}
 	sess.mu.Unlock()
-	select {
-	case resp, ok := <-l:
-		if !ok {
-			return nil, nil, SessionClosedBeforeReceiving
-		}
-		if resp.err != nil {
-			return resp.hdr, resp.resp, resp.err
-		}
-		return resp.hdr, resp.resp, nil
-	case <-ctx.Done():
-		return nil, nil, ctx.Err()
+	if ok {
+		req.done(response{err: err})
 	}
 }
 
@@ -565,11 +850,20 @@ func (sess *Session) resetSentMapPeriodically(ctx context.Context) {
 			// This helps in releasing memory occupied by old map's buckets.
 			// For a deeper dive into the memory behavior of Go maps, you can refer to:
 			// https://teivah.medium.com/maps-and-memory-leaks-in-go-a85ebe6e7e69
-			newSent := make(map[uint32]chan response)
-			for i, responses := range sess.sent {
-				newSent[i] = responses
+			newSent := make(map[uint32]*pending)
+			for i, req := range sess.sent {
+				newSent[i] = req
 			}
 			sess.sent = newSent
+			// Responses to requests expired before the previous reset
+			// are not expected anymore.
+			newExpired := make(map[uint32]time.Time)
+			for i, t := range sess.expired {
+				if time.Since(t) < sess.conf.MapResetInterval {
+					newExpired[i] = t
+				}
+			}
+			sess.expired = newExpired
 			sess.mu.Unlock()
 		}
 	}
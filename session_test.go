@@ -3,6 +3,9 @@ package smpp_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"testing"
 	"time"
 
@@ -256,3 +259,386 @@ func TestSMSCSession(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSMSCSessionInvalidRequests(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	bindTRxResp := bindTRx.Response("SMSC")
+	longSrc := &pdu.SubmitSm{SourceAddr: "1234567890123456789012345"}
+	highPriority := &pdu.SubmitSm{PriorityFlag: 5}
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", ShortMessage: []byte("message")}
+	// submit_sm with sequence 4 and truncated body.
+	truncated := []byte{0, 0, 0, 19, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 4, 0, 0, 0}
+	// Unknown command with sequence 5.
+	unknown := []byte{0, 0, 0, 17, 0, 0, 0x09, 0x99, 0, 0, 0, 0, 0, 0, 0, 5, 0}
+	// submit_sm with sequence 6 over the length limit.
+	oversize := append([]byte{0, 0, 0, 80, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 6}, make([]byte, 64)...)
+
+	sync := make(chan struct{})
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteRead(e.i(bindTRx)).ByteWrite(e.s(bindTRxResp)).
+		ByteRead(e.i(longSrc)).ByteWrite(e.s(&pdu.SubmitSmResp{}, pdu.StatusInvSrcAdr)).
+		ByteRead(e.i(highPriority)).ByteWrite(e.s(&pdu.SubmitSmResp{}, pdu.StatusInvPrtFlg)).
+		ByteRead(truncated).ByteWrite(e.i(pdu.GenericNack{}, pdu.StatusInvCmdLen)).
+		ByteRead(unknown).ByteWrite(e.i(pdu.GenericNack{}, pdu.StatusInvCmdID)).
+		ByteRead(oversize).ByteWrite(e.i(pdu.GenericNack{}, pdu.StatusInvCmdLen)).
+		ByteRead(e.i(submitSm)).ByteWrite(e.s(submitSm.Response("id0"))).Wait(1).
+		Closed()
+	conf := smpp.SessionConf{
+		Type:             smpp.SMSC,
+		ValidateRequests: true,
+		MaxPDULength:     64,
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			switch ctx.CommandID() {
+			case pdu.BindTransceiverID:
+				ctx.Respond(bindTRxResp, pdu.StatusOK)
+			case pdu.SubmitSmID:
+				defer close(sync)
+				sm, err := ctx.SubmitSm()
+				if err != nil {
+					t.Errorf("Handler can't get SubmitSm request %v", err)
+				}
+				if err := ctx.Respond(sm.Response("id0"), pdu.StatusOK); err != nil {
+					t.Errorf("Handler can't respond to SubmitSm request %v", err)
+				}
+			}
+		}),
+	}
+	sess := smpp.NewSession(conn, conf)
+	select {
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timeout waiting for valid request")
+	case <-sync:
+	}
+	sess.Close()
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestSMSCSessionLengthOverDiscardLimit(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	bindTRxResp := bindTRx.Response("SMSC")
+	// submit_sm with sequence 2 and length too big to be read out.
+	header := []byte{0, 0x10, 0, 0, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 2}
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteRead(e.i(bindTRx)).ByteWrite(e.s(bindTRxResp)).
+		ByteRead(header).ByteWrite(e.i(pdu.GenericNack{}, pdu.StatusInvCmdLen)).Wait(1).
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{
+		Type: smpp.SMSC,
+		Handler: smpp.HandlerFunc(func(ctx *smpp.Context) {
+			ctx.Respond(bindTRxResp, pdu.StatusOK)
+		}),
+	})
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(time.Second):
+		t.Fatal("session not closed after framing error")
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestSessionWriteErrors(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	writeErr := errors.New("broken pipe")
+	conn := mock.NewConn().ErrWrite(writeErr).Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, bindTRx); err != writeErr {
+		t.Errorf("expected write error got %v", err)
+	}
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("session not closed after write error")
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+
+	// Peer that never reads.
+	client, server := net.Pipe()
+	defer server.Close()
+	sess = smpp.NewSession(client, smpp.SessionConf{WriteTimeout: 20 * time.Millisecond})
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, _, err := sess.Send(ctx, bindTRx)
+	if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+		t.Errorf("expected write timeout got %v", err)
+	}
+	select {
+	case <-sess.NotifyClosed():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("session not closed after write timeout")
+	}
+}
+
+func TestESMESessionSendAsync(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	bindTRxResp := bindTRx.Response("SMSC")
+	submitSm := &pdu.SubmitSm{
+		SourceAddr:      "source",
+		DestinationAddr: "destination",
+		ShortMessage:    []byte("this is the message"),
+	}
+	e := newTestEncoder(0)
+	conn := mock.NewConn().
+		ByteWrite(e.i(bindTRx)).ByteRead(e.s(bindTRxResp)).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id1"))).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id2"))).
+		ByteWrite(e.i(submitSm)).ByteRead(e.s(submitSm.Response("id3"), pdu.StatusMsgQFul)).
+		ByteWrite(e.i(submitSm)).NoResp().
+		Closed()
+	sess := smpp.NewSession(conn, smpp.SessionConf{})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, bindTRx); err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		id  string
+		err error
+	}
+	results := make(chan result, 3)
+	for i := 0; i < 3; i++ {
+		err := sess.SendAsync(ctx, submitSm, func(h pdu.Header, p pdu.PDU, err error) {
+			if err != nil {
+				results <- result{err: err}
+				return
+			}
+			results <- result{id: p.(*pdu.SubmitSmResp).MessageID}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 3; i++ {
+		select {
+		case res := <-results:
+			if i < 3 && res.id != fmt.Sprintf("id%d", i) {
+				t.Errorf("response %d %+v", i, res)
+			}
+			if serr, ok := res.err.(smpp.StatusError); i == 3 && (!ok || serr.Status() != pdu.StatusMsgQFul) {
+				t.Errorf("expected status error got %+v", res)
+			}
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for responses")
+		}
+	}
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer shortCancel()
+	expired := make(chan error, 1)
+	if err := sess.SendAsync(shortCtx, submitSm, func(_ pdu.Header, _ pdu.PDU, err error) {
+		expired <- err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-expired:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected deadline error got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for expired request")
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestESMESessionLateResponse(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	bindTRxResp := bindTRx.Response("SMSC")
+	submitSm := &pdu.SubmitSm{
+		SourceAddr:      "source",
+		DestinationAddr: "destination",
+		ShortMessage:    []byte("this is the message"),
+	}
+	e := newTestEncoder(0)
+	// Response to the first submit_sm arrives together with the second one.
+	late := newTestEncoder(1).i(submitSm.Response("id1"))
+	conn := mock.NewConn().
+		ByteWrite(e.i(bindTRx)).ByteRead(e.s(bindTRxResp)).
+		ByteWrite(e.i(submitSm)).NoResp().
+		ByteWrite(e.i(submitSm)).ByteRead(append(late, e.s(submitSm.Response("id2"))...)).
+		Closed()
+	lateResp := make(chan pdu.PDU, 1)
+	sess := smpp.NewSession(conn, smpp.SessionConf{
+		SendWinSize: 1,
+		LateResponse: func(hdr pdu.Header, resp pdu.PDU) {
+			if hdr.Sequence() != 2 {
+				t.Errorf("late response with sequence %d", hdr.Sequence())
+			}
+			lateResp <- resp
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := sess.Send(ctx, bindTRx); err != nil {
+		t.Fatal(err)
+	}
+	shortCtx, shortCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer shortCancel()
+	if _, _, err := sess.Send(shortCtx, submitSm); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline error got %v", err)
+	}
+	_, resp, err := sess.Send(ctx, submitSm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := resp.(*pdu.SubmitSmResp).MessageID; id != "id2" {
+		t.Errorf("expected response id2 got %s", id)
+	}
+	select {
+	case resp := <-lateResp:
+		if id := resp.(*pdu.SubmitSmResp).MessageID; id != "id1" {
+			t.Errorf("expected late response id1 got %s", id)
+		}
+	case <-ctx.Done():
+		t.Fatal("late response not reported")
+	}
+	if err := sess.Close(); err != nil {
+		t.Errorf("Got error during session close %+v", err)
+	}
+	for _, err := range conn.Validate() {
+		t.Error(err)
+	}
+}
+
+func TestESMESessionWindowWait(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	sess := smpp.NewSession(client, smpp.SessionConf{SendWinSize: 1, WindowWait: true})
+	dec := pdu.NewDecoder(server)
+	enc := pdu.NewEncoder(server, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	send := func(p pdu.PDU) <-chan error {
+		res := make(chan error, 1)
+		go func() {
+			_, _, err := sess.Send(ctx, p)
+			res <- err
+		}()
+		return res
+	}
+	respond := func(expected pdu.CommandID) {
+		h, p, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.CommandID() != expected {
+			t.Fatalf("expected %s got %s", expected, h.CommandID())
+		}
+		resp, _ := pdu.NewResponse(p.CommandID())
+		if _, err := enc.Encode(resp, pdu.EncodeSeq(h.Sequence())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bound := send(&pdu.BindTRx{SystemID: "ESME"})
+	respond(pdu.BindTransceiverID)
+	if err := <-bound; err != nil {
+		t.Fatal(err)
+	}
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	first := send(submitSm)
+	h, _, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := send(submitSm)
+	time.Sleep(20 * time.Millisecond)
+	enquired := send(pdu.EnquireLink{})
+	time.Sleep(20 * time.Millisecond)
+	shortCtx, shortCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer shortCancel()
+	if _, _, err := sess.Send(shortCtx, submitSm); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error while waiting for window got %v", err)
+	}
+	if _, err := enc.Encode(submitSm.Response("id1"), pdu.EncodeSeq(h.Sequence())); err != nil {
+		t.Fatal(err)
+	}
+	// enquire_link gets the slot before the submit_sm queued earlier.
+	respond(pdu.EnquireLinkID)
+	respond(pdu.SubmitSmID)
+	for i, res := range []<-chan error{first, enquired, second} {
+		if err := <-res; err != nil {
+			t.Errorf("request %d: %v", i, err)
+		}
+	}
+	sess.Close()
+}
+
+func TestSessionCloseFlushesQueue(t *testing.T) {
+	bindTRx := &pdu.BindTRx{SystemID: "ESME"}
+	submitSm := &pdu.SubmitSm{SourceAddr: "source", DestinationAddr: "destination"}
+	client, server := net.Pipe()
+	defer server.Close()
+	sess := smpp.NewSession(client, smpp.SessionConf{})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	dec := pdu.NewDecoder(server)
+	go func() {
+		if h, _, err := dec.Decode(); err == nil {
+			pdu.NewEncoder(server, nil).Encode(bindTRx.Response("SMSC"), pdu.EncodeSeq(h.Sequence()))
+		}
+	}()
+	if _, _, err := sess.Send(ctx, bindTRx); err != nil {
+		t.Fatal(err)
+	}
+	// First PDU blocks the writer until the peer reads, second one waits
+	// in the queue when the session is closed.
+	for i := 0; i < 2; i++ {
+		if err := sess.SendAsync(ctx, submitSm, func(pdu.Header, pdu.PDU, error) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- sess.Close() }()
+	for i := 0; i < 2; i++ {
+		_, p, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("decoding submit_sm %d: %v", i, err)
+		}
+		if p.CommandID() != pdu.SubmitSmID {
+			t.Errorf("expected submit_sm got %s", p.CommandID())
+		}
+	}
+	if err := <-closed; err != nil {
+		t.Error(err)
+	}
+	if _, _, err := dec.Decode(); err == nil {
+		t.Error("connection not closed after flushing")
+	}
+}
+
+func TestSessionCloseStalledPeer(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	sess := smpp.NewSession(client, smpp.SessionConf{
+		WriteTimeout: time.Hour,
+		CloseTimeout: 20 * time.Millisecond,
+	})
+	// Peer never reads so the bind stays in the writer.
+	err := sess.SendAsync(context.Background(), &pdu.BindTRx{SystemID: "ESME"}, func(pdu.Header, pdu.PDU, error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- sess.Close() }()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close blocked by stalled peer")
+	}
+}
@@ -76,6 +76,9 @@ type BindConf struct {
 	AddrTon    int
 	AddrNpi    int
 	AddrRange  string
+	// Limiters provides limiter shared by all sessions bound with the same
+	// SystemID. It's combined with SessionConf.Limiter.
+	Limiters *LimiterRegistry
 }
 
 func bind(req pdu.PDU, sc SessionConf, bc BindConf) (*Session, error) {
@@ -83,6 +86,9 @@ func bind(req pdu.PDU, sc SessionConf, bc BindConf) (*Session, error) {
 	if err != nil {
 		return nil, err
 	}
+	if bc.Limiters != nil {
+		sc.Limiter = Limiters(sc.Limiter, bc.Limiters.Get(bc.SystemID))
+	}
 	sess := NewSession(conn, sc)
 	timeout := sc.WindowTimeout
 	if timeout == 0 {
@@ -165,7 +171,7 @@ func SendBindRx(ctx context.Context, sess *Session, p *pdu.BindRx) (*pdu.BindRxR
 	var tresp *pdu.BindRxResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.BindRxResp)
+		tresp, _ = resp.(*pdu.BindRxResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -187,7 +193,7 @@ func SendBindTx(ctx context.Context, sess *Session, p *pdu.BindTx) (*pdu.BindTxR
 	var tresp *pdu.BindTxResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.BindTxResp)
+		tresp, _ = resp.(*pdu.BindTxResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -209,7 +215,7 @@ func SendQuerySm(ctx context.Context, sess *Session, p *pdu.QuerySm) (*pdu.Query
 	var tresp *pdu.QuerySmResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.QuerySmResp)
+		tresp, _ = resp.(*pdu.QuerySmResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -231,7 +237,7 @@ func SendSubmitSm(ctx context.Context, sess *Session, p *pdu.SubmitSm) (*pdu.Sub
 	var tresp *pdu.SubmitSmResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.SubmitSmResp)
+		tresp, _ = resp.(*pdu.SubmitSmResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -253,7 +259,7 @@ func SendDeliverSm(ctx context.Context, sess *Session, p *pdu.DeliverSm) (*pdu.D
 	var tresp *pdu.DeliverSmResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.DeliverSmResp)
+		tresp, _ = resp.(*pdu.DeliverSmResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -275,7 +281,7 @@ func SendUnbind(ctx context.Context, sess *Session, p *pdu.Unbind) (*pdu.UnbindR
 	var tresp *pdu.UnbindResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.UnbindResp)
+		tresp, _ = resp.(*pdu.UnbindResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -297,7 +303,7 @@ func SendReplaceSm(ctx context.Context, sess *Session, p *pdu.ReplaceSm) (*pdu.R
 	var tresp *pdu.ReplaceSmResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.ReplaceSmResp)
+		tresp, _ = resp.(*pdu.ReplaceSmResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -319,7 +325,7 @@ func SendCancelSm(ctx context.Context, sess *Session, p *pdu.CancelSm) (*pdu.Can
 	var tresp *pdu.CancelSmResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.CancelSmResp)
+		tresp, _ = resp.(*pdu.CancelSmResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -341,7 +347,7 @@ func SendBindTRx(ctx context.Context, sess *Session, p *pdu.BindTRx) (*pdu.BindT
 	var tresp *pdu.BindTRxResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.BindTRxResp)
+		tresp, _ = resp.(*pdu.BindTRxResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -372,7 +378,7 @@ func SendEnquireLink(ctx context.Context, sess *Session, p *pdu.EnquireLink) (*p
 	var tresp *pdu.EnquireLinkResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.EnquireLinkResp)
+		tresp, _ = resp.(*pdu.EnquireLinkResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -394,7 +400,7 @@ func SendSubmitMulti(ctx context.Context, sess *Session, p *pdu.SubmitMulti) (*p
 	var tresp *pdu.SubmitMultiResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.SubmitMultiResp)
+		tresp, _ = resp.(*pdu.SubmitMultiResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -425,7 +431,7 @@ func SendDataSm(ctx context.Context, sess *Session, p *pdu.DataSm) (*pdu.DataSmR
 	var tresp *pdu.DataSmResp
 	_, resp, err := sess.Send(ctx, p)
 	if resp != nil {
-		tresp = resp.(*pdu.DataSmResp)
+		tresp, _ = resp.(*pdu.DataSmResp)
 	}
 	if err != nil {
 		return tresp, err
@@ -0,0 +1,268 @@
+package smpp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RecordStore persists records of messages waiting for the receipt so they
+// can be correlated after restart.
+type RecordStore interface {
+	// Put stores record replacing one with the same message id.
+	Put(rec Record) error
+	// Delete removes record with the message id.
+	Delete(messageID string) error
+	// Load returns all stored records.
+	Load() ([]Record, error)
+}
+
+// compactMin is number of obsolete entries before the file is compacted.
+const compactMin = 1024
+
+// fileEntry is single line of the store file.
+type fileEntry struct {
+	Op        string            `json:"op"`
+	MessageID string            `json:"id"`
+	Metadata  map[string]string `json:"meta,omitempty"`
+	Submitted *time.Time        `json:"ts,omitempty"`
+}
+
+func putEntry(rec Record) fileEntry {
+	return fileEntry{
+		Op:        "put",
+		MessageID: rec.MessageID,
+		Metadata:  rec.Metadata,
+		Submitted: &rec.Submitted,
+	}
+}
+
+// FileStore is RecordStore appending changes to the file as JSON lines.
+// File is compacted once it holds more obsolete entries than live records.
+// Changes are written to the operating system and survive crash of the
+// process but may be lost on power failure unless FileStoreSync is used.
+type FileStore struct {
+	path    string
+	sync    bool
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	live    map[string]Record
+	garbage int
+}
+
+// FileStoreOption configures FileStore.
+type FileStoreOption func(*FileStore)
+
+// FileStoreSync makes the store flush every change to the disk, and the
+// directory after compaction, before it returns.
+func FileStoreSync() FileStoreOption {
+	return func(fs *FileStore) {
+		fs.sync = true
+	}
+}
+
+// OpenFileStore opens or creates the store file. Partially written entry at
+// the end of the file, e.g. after crash, is discarded as well as corrupted
+// entries.
+func OpenFileStore(path string, opts ...FileStoreOption) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fs := &FileStore{
+		path: path,
+		f:    f,
+		live: make(map[string]Record),
+	}
+	for _, o := range opts {
+		o(fs)
+	}
+	if err := fs.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return fs, nil
+}
+
+// replay reads the file and positions it for appending after the last
+// complete entry.
+func (fs *FileStore) replay() error {
+	r := bufio.NewReader(fs.f)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
+		var e fileEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			// Corrupted entry is removed by compaction.
+			fs.garbage++
+			continue
+		}
+		fs.apply(e)
+	}
+	fs.size = offset
+	return fs.truncate()
+}
+
+// truncate removes anything written after the last complete entry.
+//
+// Must be guarded by mutex.
+func (fs *FileStore) truncate() error {
+	if err := fs.f.Truncate(fs.size); err != nil {
+		return err
+	}
+	_, err := fs.f.Seek(fs.size, io.SeekStart)
+	return err
+}
+
+// Must be guarded by mutex.
+func (fs *FileStore) apply(e fileEntry) {
+	if _, ok := fs.live[e.MessageID]; ok {
+		fs.garbage++
+	}
+	switch e.Op {
+	case "put":
+		rec := Record{MessageID: e.MessageID, Metadata: e.Metadata}
+		if e.Submitted != nil {
+			rec.Submitted = *e.Submitted
+		}
+		fs.live[e.MessageID] = rec
+	case "del":
+		delete(fs.live, e.MessageID)
+		fs.garbage++
+	}
+}
+
+// Must be guarded by mutex.
+func (fs *FileStore) append(e fileEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	n, err := fs.f.Write(append(b, '\n'))
+	if err == nil && fs.sync {
+		err = fs.f.Sync()
+	}
+	if err != nil {
+		// Partially written entry would corrupt the following ones.
+		if terr := fs.truncate(); terr != nil {
+			return fmt.Errorf("smpp: truncating store after %v: %v", err, terr)
+		}
+		return err
+	}
+	fs.size += int64(n)
+	fs.apply(e)
+	if fs.garbage >= compactMin && fs.garbage > len(fs.live) {
+		return fs.compact()
+	}
+	return nil
+}
+
+// Put implements RecordStore interface.
+func (fs *FileStore) Put(rec Record) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.append(putEntry(rec))
+}
+
+// Delete implements RecordStore interface.
+func (fs *FileStore) Delete(messageID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.live[messageID]; !ok {
+		return nil
+	}
+	return fs.append(fileEntry{Op: "del", MessageID: messageID})
+}
+
+// Load implements RecordStore interface.
+func (fs *FileStore) Load() ([]Record, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	recs := make([]Record, 0, len(fs.live))
+	for _, rec := range fs.live {
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// Compact rewrites the file with live records only.
+func (fs *FileStore) Compact() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.compact()
+}
+
+// Must be guarded by mutex.
+func (fs *FileStore) compact() error {
+	tmp, err := os.OpenFile(fs.path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, rec := range fs.live {
+		if err = enc.Encode(putEntry(rec)); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	var size int64
+	if err == nil {
+		size, err = tmp.Seek(0, io.SeekCurrent)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fs.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	fs.f.Close()
+	fs.f = tmp
+	fs.size = size
+	fs.garbage = 0
+	if fs.sync {
+		return syncDir(filepath.Dir(fs.path))
+	}
+	return nil
+}
+
+// syncDir flushes directory entries so that renamed file survives power
+// failure.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Close closes the store file.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.f.Close()
+}
@@ -0,0 +1,179 @@
+package smpp_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func tempStorePath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "smpp-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "records"), func() { os.RemoveAll(dir) }
+}
+
+func TestFileStore(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+	fs, err := smpp.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if err := fs.Put(smpp.Record{MessageID: id, Metadata: map[string]string{"id": id}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Delete("2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate crash in the middle of writing.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"op":"put","id":"4"`))
+	f.Close()
+
+	fs, err = smpp.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records got %+v", recs)
+	}
+	for _, rec := range recs {
+		if rec.MessageID != rec.Metadata["id"] || rec.MessageID == "2" {
+			t.Errorf("unexpected record %+v", rec)
+		}
+	}
+	before, _ := os.Stat(path)
+	if err := fs.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Put(smpp.Record{MessageID: "5"}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("file not compacted, size %d before %d", after.Size(), before.Size())
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fs, err = smpp.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	if recs, _ := fs.Load(); len(recs) != 3 {
+		t.Errorf("expected 3 records after compaction got %+v", recs)
+	}
+}
+
+func TestFileStoreCorruptedEntry(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+	open := func() *smpp.FileStore {
+		t.Helper()
+		fs, err := smpp.OpenFileStore(path, smpp.FileStoreSync())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+	fs := open()
+	if err := fs.Put(smpp.Record{MessageID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("{\"op\":\x00\n"))
+	f.Close()
+
+	fs = open()
+	if err := fs.Put(smpp.Record{MessageID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+	fs = open()
+	if recs, _ := fs.Load(); len(recs) != 2 {
+		t.Errorf("expected 2 records around corrupted entry got %+v", recs)
+	}
+	if err := fs.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	fs.Close()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 2 {
+		t.Errorf("expected 2 entries after compaction got %q", b)
+	}
+}
+
+func TestTrackerStoreRestart(t *testing.T) {
+	path, cleanup := tempStorePath(t)
+	defer cleanup()
+	fs, err := smpp.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := smpp.NewTracker(smpp.TrackerConf{Store: fs, SubmitIDBase: 16, ReceiptIDBase: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Track("01a2b", map[string]string{"customer": "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	tr.Close()
+	if recs, _ := fs.Load(); len(recs) != 1 || recs[0].MessageID != "1A2B" {
+		t.Errorf("record not stored under converted id %+v", recs)
+	}
+	fs.Close()
+
+	fs, err = smpp.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	receipts := make(chan smpp.Receipt, 1)
+	tr, err = smpp.NewTracker(smpp.TrackerConf{
+		Store:         fs,
+		OnReceipt:     func(r smpp.Receipt) { receipts <- r },
+		SubmitIDBase:  16,
+		ReceiptIDBase: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	sm := &pdu.DeliverSm{ShortMessage: []byte("id:6699 sub:001 dlvrd:001 submit date:1702281424 done date:1702281424 stat:DELIVRD err:000 text:")}
+	if ok, err := tr.Deliver(sm); !ok || err != nil {
+		t.Fatalf("receipt not matched after restart %t %v", ok, err)
+	}
+	if r := <-receipts; r.Metadata["customer"] != "c1" {
+		t.Errorf("unexpected receipt %+v", r)
+	}
+	if recs, _ := fs.Load(); len(recs) != 0 {
+		t.Errorf("record not removed from store %+v", recs)
+	}
+}
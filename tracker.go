@@ -0,0 +1,290 @@
+package smpp
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// Record is submitted message waiting for the delivery receipt.
+type Record struct {
+	// MessageID assigned by SMSC in submit_sm_resp.
+	MessageID string
+	// Metadata provided by the user when the message was tracked.
+	Metadata map[string]string
+	// Submitted is the time the message was tracked.
+	Submitted time.Time
+}
+
+// Receipt is the outcome of the tracked message.
+type Receipt struct {
+	Record
+	// Stat is the final state of the message, empty if timed out.
+	Stat pdu.DeliveryStat
+	// Err is network or SMSC error code from the receipt.
+	Err pdu.DeliveryErr
+	// Receipt parsed from short_message, nil if the state was taken from
+	// TLVs or the message timed out.
+	Receipt *pdu.DeliveryReceipt
+	// TimedOut is set if no final receipt arrived within TrackerConf.Timeout.
+	TimedOut bool
+}
+
+// TrackerConf configures Tracker.
+type TrackerConf struct {
+	// OnReceipt is called once for every tracked message with the final
+	// receipt or after the timeout.
+	OnReceipt func(Receipt)
+	// Timeout after which message without final receipt is reported as
+	// timed out. Default is 72 hours.
+	Timeout time.Duration
+	// CheckInterval of timed out messages. Default is 1 minute or Timeout
+	// if shorter.
+	CheckInterval time.Duration
+	// Store persists tracked messages. Records are kept only in memory if nil.
+	Store RecordStore
+	// Logger reports errors of the store when messages time out.
+	Logger Logger
+	// ReceiptFormat of receipts in short_message. Default is
+	// pdu.DefaultReceiptFormat.
+	ReceiptFormat pdu.ReceiptFormat
+	// SubmitIDBase is numeric base of message ids in submit_sm_resp and
+	// receipted_message_id TLV. If set, ids are tracked without leading
+	// zeros and with upper case letters, otherwise they must match exactly.
+	SubmitIDBase int
+	// ReceiptIDBase is numeric base of message ids in receipts in
+	// short_message, e.g. 10 if SMSC reports hexadecimal ids in decimal.
+	// Ids are converted to SubmitIDBase, decimal if it isn't set, when
+	// the bases differ. Default is SubmitIDBase.
+	ReceiptIDBase int
+	// EarlyReceiptTimeout is how long final receipt of message which isn't
+	// tracked yet is kept in case Track is called after the receipt
+	// arrived. Default is 1 minute.
+	EarlyReceiptTimeout time.Duration
+}
+
+// Tracker correlates delivery receipts with submitted messages by message
+// id. Ids are converted once to the form of submit_sm_resp as configured by
+// TrackerConf.SubmitIDBase and TrackerConf.ReceiptIDBase.
+type Tracker struct {
+	conf    TrackerConf
+	mu      sync.Mutex
+	records map[string]Record
+	early   map[string]earlyReceipt
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// earlyReceipt is final receipt which arrived before the message was tracked.
+type earlyReceipt struct {
+	Receipt
+	received time.Time
+}
+
+// NewTracker creates tracker with messages loaded from the store and starts
+// checking for timed out messages. Tracker must be closed after use.
+func NewTracker(conf TrackerConf) (*Tracker, error) {
+	if conf.OnReceipt == nil {
+		conf.OnReceipt = func(Receipt) {}
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = 72 * time.Hour
+	}
+	if conf.CheckInterval == 0 {
+		conf.CheckInterval = time.Minute
+		if conf.Timeout < conf.CheckInterval {
+			conf.CheckInterval = conf.Timeout
+		}
+	}
+	if conf.Logger == nil {
+		conf.Logger = DefaultLogger{}
+	}
+	if conf.ReceiptIDBase == 0 {
+		conf.ReceiptIDBase = conf.SubmitIDBase
+	}
+	if conf.EarlyReceiptTimeout == 0 {
+		conf.EarlyReceiptTimeout = time.Minute
+	}
+	t := &Tracker{
+		conf:    conf,
+		records: make(map[string]Record),
+		early:   make(map[string]earlyReceipt),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if conf.Store != nil {
+		recs, err := conf.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range recs {
+			t.records[rec.MessageID] = rec
+		}
+	}
+	go t.expireLoop()
+	return t, nil
+}
+
+// convertID parses message id in the base and formats it in SubmitIDBase,
+// zero base is decimal. Id is returned unchanged if it isn't a number or
+// neither SubmitIDBase is set nor the base differs from decimal.
+func (t *Tracker) convertID(id string, base int) string {
+	to := t.conf.SubmitIDBase
+	if to == 0 {
+		if base == 0 || base == 10 {
+			return id
+		}
+		to = 10
+	}
+	if base == 0 {
+		base = 10
+	}
+	v, err := strconv.ParseUint(id, base, 64)
+	if err != nil {
+		return id
+	}
+	return strings.ToUpper(strconv.FormatUint(v, to))
+}
+
+// Track records message submitted with the id returned in submit_sm_resp.
+// Record is stored with the id converted as configured by SubmitIDBase.
+// If the final receipt of the message already arrived the callback is
+// called before Track returns.
+func (t *Tracker) Track(messageID string, metadata map[string]string) error {
+	rec := Record{
+		MessageID: t.convertID(messageID, t.conf.SubmitIDBase),
+		Metadata:  metadata,
+		Submitted: time.Now(),
+	}
+	if t.conf.Store != nil {
+		if err := t.conf.Store.Put(rec); err != nil {
+			return err
+		}
+	}
+	t.mu.Lock()
+	er, ok := t.early[rec.MessageID]
+	if !ok {
+		t.records[rec.MessageID] = rec
+		t.mu.Unlock()
+		return nil
+	}
+	delete(t.early, rec.MessageID)
+	t.mu.Unlock()
+	var err error
+	if t.conf.Store != nil {
+		err = t.conf.Store.Delete(rec.MessageID)
+	}
+	er.Record = rec
+	t.conf.OnReceipt(er.Receipt)
+	return err
+}
+
+// Must be guarded by mutex.
+func (t *Tracker) remove(messageID string) error {
+	delete(t.records, messageID)
+	if t.conf.Store != nil {
+		return t.conf.Store.Delete(messageID)
+	}
+	return nil
+}
+
+// Len returns number of messages waiting for the receipt.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.records)
+}
+
+// Deliver matches delivery receipt with tracked message. Message id and
+// state are taken from receipted_message_id and message_state TLVs if
+// present, otherwise from receipt in short_message. Callback is called if
+// the state is final. It reports whether the receipt matched any message,
+// error of the store is returned after the callback. Final receipt which
+// didn't match is kept for TrackerConf.EarlyReceiptTimeout and matched by
+// Track.
+func (t *Tracker) Deliver(sm *pdu.DeliverSm) (bool, error) {
+	var r Receipt
+	id, ok := "", false
+	if sm.Options != nil {
+		id = t.convertID(sm.Options.ReceiptedMessageID(), t.conf.SubmitIDBase)
+		state, sok := sm.Options.GetSingle(pdu.TagMessageState)
+		ok = id != "" && sok
+		r.Stat = pdu.DelStatMap[uint8(state)]
+	}
+	if !ok {
+		dr, err := t.conf.ReceiptFormat.Parse(string(sm.ShortMessage))
+		if err != nil {
+			return false, err
+		}
+		if id == "" {
+			id = t.convertID(dr.Id, t.conf.ReceiptIDBase)
+		}
+		r.Stat, r.Err, r.Receipt = dr.Stat, dr.Err, dr
+	}
+	final := r.Stat != pdu.DelStatEnRoute && r.Stat != ""
+	t.mu.Lock()
+	rec, ok := t.records[id]
+	if !ok {
+		if final && id != "" {
+			t.early[id] = earlyReceipt{Receipt: r, received: time.Now()}
+		}
+		t.mu.Unlock()
+		return false, nil
+	}
+	var err error
+	if final {
+		err = t.remove(id)
+	}
+	t.mu.Unlock()
+	if final {
+		r.Record = rec
+		t.conf.OnReceipt(r)
+	}
+	return true, err
+}
+
+func (t *Tracker) expireLoop() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.conf.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.expire(time.Now())
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *Tracker) expire(now time.Time) {
+	var expired []Record
+	t.mu.Lock()
+	for id, rec := range t.records {
+		if now.Sub(rec.Submitted) >= t.conf.Timeout {
+			if err := t.remove(id); err != nil {
+				t.conf.Logger.ErrorF("removing timed out message %s: %+v", rec.MessageID, err)
+			}
+			expired = append(expired, rec)
+		}
+	}
+	for id, er := range t.early {
+		if now.Sub(er.received) >= t.conf.EarlyReceiptTimeout {
+			delete(t.early, id)
+		}
+	}
+	t.mu.Unlock()
+	for _, rec := range expired {
+		t.conf.OnReceipt(Receipt{Record: rec, TimedOut: true})
+	}
+}
+
+// Close stops checking for timed out messages.
+func (t *Tracker) Close() error {
+	close(t.stop)
+	<-t.done
+	return nil
+}
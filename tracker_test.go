@@ -0,0 +1,200 @@
+package smpp_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go"
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func TestTracker(t *testing.T) {
+	receipts := make(chan smpp.Receipt, 3)
+	tr, err := smpp.NewTracker(smpp.TrackerConf{
+		OnReceipt:     func(r smpp.Receipt) { receipts <- r },
+		SubmitIDBase:  16,
+		ReceiptIDBase: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	tr.Track("1a2b", map[string]string{"customer": "hex"})
+	tr.Track("0042", map[string]string{"customer": "tlv"})
+
+	// Receipt reports hexadecimal id in decimal.
+	enroute := &pdu.DeliverSm{ShortMessage: []byte("id:6699 sub:001 dlvrd:000 submit date:1702281424 done date:1702281424 stat:ENROUTE err:000 text:")}
+	if ok, err := tr.Deliver(enroute); !ok || err != nil {
+		t.Fatalf("enroute receipt not matched %t %v", ok, err)
+	}
+	select {
+	case r := <-receipts:
+		t.Fatalf("callback called for intermediate state %+v", r)
+	default:
+	}
+	delivered := &pdu.DeliverSm{ShortMessage: []byte("id:0000006699 sub:001 dlvrd:001 submit date:1702281424 done date:1702281424 stat:DELIVRD err:000 text:")}
+	if ok, err := tr.Deliver(delivered); !ok || err != nil {
+		t.Fatalf("delivered receipt not matched %t %v", ok, err)
+	}
+	r := <-receipts
+	if r.MessageID != "1A2B" || r.Metadata["customer"] != "hex" || r.Stat != pdu.DelStatDelivered || r.Receipt == nil {
+		t.Errorf("unexpected receipt %+v", r)
+	}
+
+	tlv := &pdu.DeliverSm{Options: pdu.NewOptions().SetReceiptedMessageID("42").SetMessageState(3)}
+	if ok, err := tr.Deliver(tlv); !ok || err != nil {
+		t.Fatalf("TLV receipt not matched %t %v", ok, err)
+	}
+	r = <-receipts
+	if r.MessageID != "42" || r.Metadata["customer"] != "tlv" || r.Stat != pdu.DelStatExpired || r.Receipt != nil {
+		t.Errorf("unexpected receipt %+v", r)
+	}
+
+	if ok, _ := tr.Deliver(delivered); ok {
+		t.Error("receipt matched twice")
+	}
+	if _, err := tr.Deliver(pdu.DefaultReceiptFormat.DeliverSm(&pdu.DeliveryReceipt{Id: "1a2b", Stat: pdu.DelStatDelivered}, &pdu.SubmitSm{})); err != nil {
+		t.Error(err)
+	}
+	if _, err := tr.Deliver(&pdu.DeliverSm{ShortMessage: []byte("hello")}); err == nil {
+		t.Error("expected error for message without receipt")
+	}
+	if n := tr.Len(); n != 0 {
+		t.Errorf("%d messages still tracked", n)
+	}
+}
+
+func TestTrackerExactIDs(t *testing.T) {
+	tr, err := smpp.NewTracker(smpp.TrackerConf{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	tr.Track("1A2B", nil)
+	tr.Track("0042", nil)
+	for _, id := range []string{"6699", "1a2b", "42"} {
+		sm := &pdu.DeliverSm{Options: pdu.NewOptions().SetReceiptedMessageID(id).SetMessageState(2)}
+		if ok, err := tr.Deliver(sm); ok || err != nil {
+			t.Errorf("receipt for %s matched %t %v", id, ok, err)
+		}
+	}
+	sm := &pdu.DeliverSm{Options: pdu.NewOptions().SetReceiptedMessageID("0042").SetMessageState(2)}
+	if ok, err := tr.Deliver(sm); !ok || err != nil {
+		t.Errorf("receipt not matched %t %v", ok, err)
+	}
+}
+
+func TestTrackerTimeout(t *testing.T) {
+	receipts := make(chan smpp.Receipt, 1)
+	tr, err := smpp.NewTracker(smpp.TrackerConf{
+		OnReceipt:     func(r smpp.Receipt) { receipts <- r },
+		Timeout:       20 * time.Millisecond,
+		CheckInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	tr.Track("1", nil)
+	select {
+	case r := <-receipts:
+		if !r.TimedOut || r.MessageID != "1" {
+			t.Errorf("unexpected receipt %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message didn't time out")
+	}
+	if n := tr.Len(); n != 0 {
+		t.Errorf("%d messages still tracked", n)
+	}
+}
+
+func TestTrackerEarlyReceipt(t *testing.T) {
+	receipts := make(chan smpp.Receipt, 1)
+	tr, err := smpp.NewTracker(smpp.TrackerConf{
+		OnReceipt:           func(r smpp.Receipt) { receipts <- r },
+		ReceiptIDBase:       16,
+		EarlyReceiptTimeout: 20 * time.Millisecond,
+		CheckInterval:       5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	// Receipt reports decimal id in hexadecimal before the message is tracked.
+	delivered := &pdu.DeliverSm{ShortMessage: []byte("id:1A2B sub:001 dlvrd:001 submit date:1702281424 done date:1702281424 stat:DELIVRD err:000 text:")}
+	if ok, err := tr.Deliver(delivered); ok || err != nil {
+		t.Fatalf("receipt matched before tracking %t %v", ok, err)
+	}
+	if err := tr.Track("6699", map[string]string{"customer": "early"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-receipts:
+		if r.MessageID != "6699" || r.Metadata["customer"] != "early" || r.Stat != pdu.DelStatDelivered {
+			t.Errorf("unexpected receipt %+v", r)
+		}
+	default:
+		t.Fatal("early receipt not matched by Track")
+	}
+	if n := tr.Len(); n != 0 {
+		t.Errorf("%d messages still tracked", n)
+	}
+
+	if ok, err := tr.Deliver(delivered); ok || err != nil {
+		t.Fatalf("receipt matched before tracking %t %v", ok, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := tr.Track("6699", nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-receipts:
+		t.Errorf("receipt matched after early receipt timeout %+v", r)
+	default:
+	}
+	if n := tr.Len(); n != 1 {
+		t.Errorf("%d messages tracked", n)
+	}
+}
+
+// blockingStore blocks Put until released.
+type blockingStore struct {
+	put     chan struct{}
+	release chan struct{}
+}
+
+func (s blockingStore) Put(smpp.Record) error {
+	s.put <- struct{}{}
+	<-s.release
+	return nil
+}
+
+func (s blockingStore) Delete(string) error          { return nil }
+func (s blockingStore) Load() ([]smpp.Record, error) { return nil, nil }
+
+func TestTrackerStoreOutsideLock(t *testing.T) {
+	store := blockingStore{put: make(chan struct{}), release: make(chan struct{})}
+	tr, err := smpp.NewTracker(smpp.TrackerConf{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	tracked := make(chan error, 1)
+	go func() { tracked <- tr.Track("1", nil) }()
+	<-store.put
+	counted := make(chan int, 1)
+	go func() { counted <- tr.Len() }()
+	select {
+	case <-counted:
+	case <-time.After(time.Second):
+		t.Error("tracker locked during store write")
+	}
+	close(store.release)
+	if err := <-tracked; err != nil {
+		t.Fatal(err)
+	}
+	if n := tr.Len(); n != 1 {
+		t.Errorf("%d messages tracked", n)
+	}
+}
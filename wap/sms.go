@@ -0,0 +1,97 @@
+package wap
+
+import (
+	"errors"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// Maximal user data length of a single short message in octets.
+const maxUserData = 140
+
+// Datagram is application port addressed payload.
+type Datagram struct {
+	SrcPort int
+	DstPort int
+	Payload []byte
+}
+
+// SubmitSms creates submit_sm PDUs carrying datagram with 16-bit port
+// addressing. Payloads that don't fit into one message are segmented and
+// every segment gets concatenation element with reference ref.
+// Template is used for all PDU fields except data coding and short message.
+func SubmitSms(template pdu.SubmitSm, dg Datagram, ref int) ([]*pdu.SubmitSm, error) {
+	udh := pdu.UDH{}
+	udh.SetPorts(pdu.Ports{Src: dg.SrcPort, Dst: dg.DstPort, Wide: true})
+	size := maxUserData - udh.Len()
+	var parts [][]byte
+	if len(dg.Payload) <= size {
+		parts = [][]byte{dg.Payload}
+	} else {
+		udh.SetConcat(pdu.Concat{Ref: ref, Total: 1, Seq: 1})
+		size = maxUserData - udh.Len()
+		for b := dg.Payload; len(b) > 0; {
+			n := size
+			if n > len(b) {
+				n = len(b)
+			}
+			parts = append(parts, b[:n])
+			b = b[n:]
+		}
+	}
+	if len(parts) > 0xFF {
+		return nil, errors.New("wap: payload too long")
+	}
+	out := make([]*pdu.SubmitSm, len(parts))
+	for i, part := range parts {
+		sm := template
+		sm.DataCoding = pdu.OctetDataCoding
+		h := append(pdu.UDH{}, udh...)
+		if len(parts) > 1 {
+			h.SetConcat(pdu.Concat{Ref: ref, Total: len(parts), Seq: i + 1})
+		}
+		if err := sm.SetUDH(h, part); err != nil {
+			return nil, err
+		}
+		out[i] = &sm
+	}
+	return out, nil
+}
+
+// PushSubmitSms creates submit_sm PDUs carrying push message to the WAP
+// Push port.
+func PushSubmitSms(template pdu.SubmitSm, p Push, ref int) ([]*pdu.SubmitSm, error) {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return SubmitSms(template, Datagram{SrcPort: WSPPort, DstPort: PushPort, Payload: b}, ref)
+}
+
+// Decode extracts port addressed datagram from deliver_sm. Concatenated
+// messages should be reassembled before decoding, see smpp.Reassembler.
+func Decode(sm *pdu.DeliverSm) (Datagram, error) {
+	msg := sm.ShortMessage
+	if len(msg) == 0 && sm.Options != nil {
+		msg, _ = sm.Options.Get(pdu.TagMessagePayload)
+	}
+	if sm.EsmClass.Feature&pdu.UDHIEsmFeat != 0 {
+		udh, content, err := pdu.ParseUDH(msg)
+		if err != nil {
+			return Datagram{}, err
+		}
+		if p, ok := udh.Ports(); ok {
+			return Datagram{SrcPort: p.Src, DstPort: p.Dst, Payload: content}, nil
+		}
+		// Ports are in TLVs, payload is the message without UDH.
+		msg = content
+	}
+	if sm.Options != nil {
+		src, srcOk := sm.Options.GetDouble(pdu.TagSourcePort)
+		dst, dstOk := sm.Options.GetDouble(pdu.TagDestinationPort)
+		if srcOk || dstOk {
+			return Datagram{SrcPort: src, DstPort: dst, Payload: msg}, nil
+		}
+	}
+	return Datagram{}, errors.New("wap: message is not port addressed")
+}
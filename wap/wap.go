@@ -0,0 +1,328 @@
+// Package wap implements building of WAP Push messages and transport of
+// application port addressed payloads over SMPP short messages.
+//
+// Push messages are encoded as connectionless WSP push PDUs. Service
+// Indication and Service Loading content is encoded as WBXML.
+package wap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Well known WDP ports.
+const (
+	// PushPort is the destination port of WAP Push messages.
+	PushPort = 2948
+	// SecurePushPort is the destination port of secure WAP Push messages.
+	SecurePushPort = 2949
+	// WSPPort is the connectionless WSP port used as push source port.
+	WSPPort = 9200
+)
+
+// Well known push application ids used with X-Wap-Application-Id header.
+const (
+	AnyApplication      = 0x00 // x-wap-application:*
+	PushSIAApplication  = 0x01 // x-wap-application:push.sia
+	WMLApplication      = 0x02 // x-wap-application:wml.ua
+	WTAApplication      = 0x03 // x-wap-application:wta.ua
+	MMSApplication      = 0x04 // x-wap-application:mms.ua
+	SyncMLApplication   = 0x05 // x-wap-application:push.syncml
+	LocApplication      = 0x06 // x-wap-application:loc.ua
+	SyncMLDMApplication = 0x07 // x-wap-application:syncml.dm
+	DRMApplication      = 0x08 // x-wap-application:drm.ua
+	EMNApplication      = 0x09 // x-wap-application:emn.ua
+)
+
+const (
+	pushPDUType = 0x06
+	appIDHeader = 0x2F
+)
+
+var wellKnownTypes = map[string]byte{
+	"*/*":                                    0x00,
+	"text/plain":                             0x03,
+	"text/vnd.wap.wml":                       0x08,
+	"application/vnd.wap.wmlc":               0x14,
+	"application/vnd.wap.wbxml":              0x29,
+	"application/vnd.wap.sic":                0x2E,
+	"application/vnd.wap.slc":                0x30,
+	"application/vnd.wap.coc":                0x32,
+	"application/vnd.wap.connectivity-wbxml": 0x36,
+	"application/vnd.wap.mms-message":        0x3E,
+}
+
+// Push is connectionless WSP push PDU.
+type Push struct {
+	TransactionID byte
+	// ContentType is encoded as well known value if possible and as
+	// text otherwise.
+	ContentType string
+	// Params are already encoded content type parameters, for example
+	// SEC and MAC parameters of OTA provisioning documents.
+	Params []byte
+	// Headers are already encoded WSP headers. See AppIDHeader.
+	Headers []byte
+	Body    []byte
+}
+
+// AppIDHeader encodes X-Wap-Application-Id header with well known id.
+func AppIDHeader(id byte) []byte {
+	return []byte{appIDHeader | 0x80, id | 0x80}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler interface.
+func (p Push) MarshalBinary() ([]byte, error) {
+	var ct []byte
+	if v, ok := wellKnownTypes[strings.ToLower(p.ContentType)]; ok {
+		ct = []byte{v | 0x80}
+	} else if p.ContentType != "" {
+		ct = append([]byte(p.ContentType), 0)
+	} else {
+		return nil, errors.New("wap: missing push content type")
+	}
+	if len(p.Params) > 0 {
+		ct = append(valueLength(len(ct)+len(p.Params)), append(ct, p.Params...)...)
+	}
+	out := []byte{p.TransactionID, pushPDUType}
+	out = append(out, uintvar(len(ct)+len(p.Headers))...)
+	out = append(out, ct...)
+	out = append(out, p.Headers...)
+	return append(out, p.Body...), nil
+}
+
+func uintvar(n int) []byte {
+	out := []byte{byte(n & 0x7F)}
+	for n >>= 7; n > 0; n >>= 7 {
+		out = append([]byte{byte(n&0x7F) | 0x80}, out...)
+	}
+	return out
+}
+
+func valueLength(n int) []byte {
+	if n < 31 {
+		return []byte{byte(n)}
+	}
+	return append([]byte{31}, uintvar(n)...)
+}
+
+// WBXML global tokens.
+const (
+	wbxmlEnd    = 0x01
+	wbxmlStrI   = 0x03
+	wbxmlOpaque = 0xC3
+	wbxmlUTF8   = 0x6A
+	wbxmlV12    = 0x02
+	tagAttrs    = 0x80
+	tagContent  = 0x40
+)
+
+var urlValueTokens = []struct {
+	val string
+	tok byte
+}{
+	{".com/", 0x85},
+	{".edu/", 0x86},
+	{".net/", 0x87},
+	{".org/", 0x88},
+}
+
+type urlPrefix struct {
+	prefix string
+	tok    byte
+}
+
+// appendURL appends attribute start token matching url prefix and
+// the rest of the url as attribute value.
+func appendURL(out []byte, url string, prefixes []urlPrefix, plain byte) []byte {
+	tok := plain
+	for _, p := range prefixes {
+		if strings.HasPrefix(url, p.prefix) {
+			tok = p.tok
+			url = url[len(p.prefix):]
+			break
+		}
+	}
+	out = append(out, tok)
+	for url != "" {
+		i, t := -1, byte(0)
+		for _, vt := range urlValueTokens {
+			if j := strings.Index(url, vt.val); j >= 0 && (i < 0 || j < i) {
+				i, t = j, vt.tok
+			}
+		}
+		if i < 0 {
+			return appendString(out, url)
+		}
+		if i > 0 {
+			out = appendString(out, url[:i])
+		}
+		out = append(out, t)
+		url = url[i+5:]
+	}
+	return out
+}
+
+func appendString(out []byte, s string) []byte {
+	out = append(out, wbxmlStrI)
+	out = append(out, s...)
+	return append(out, 0)
+}
+
+// appendDate appends date as opaque data in packed BCD format with
+// trailing zero octets removed.
+func appendDate(out []byte, t time.Time) []byte {
+	digits := t.UTC().Format("20060102150405")
+	b := make([]byte, 0, 7)
+	for i := 0; i < len(digits); i += 2 {
+		b = append(b, (digits[i]-'0')<<4|(digits[i+1]-'0'))
+	}
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	out = append(out, wbxmlOpaque, byte(len(b)))
+	return append(out, b...)
+}
+
+// SIAction defines action attribute of Service Indication.
+type SIAction int
+
+// Service Indication actions.
+const (
+	SignalMedium SIAction = iota // Default action, not encoded.
+	SignalNone
+	SignalLow
+	SignalHigh
+	Delete
+)
+
+var siActionTokens = map[SIAction]byte{
+	SignalNone:   0x05,
+	SignalLow:    0x06,
+	SignalMedium: 0x07,
+	SignalHigh:   0x08,
+	Delete:       0x09,
+}
+
+var siHrefPrefixes = []urlPrefix{
+	{"http://www.", 0x0D},
+	{"http://", 0x0C},
+	{"https://www.", 0x0F},
+	{"https://", 0x0E},
+}
+
+// ServiceIndication is WAP Service Indication document.
+type ServiceIndication struct {
+	Href    string
+	ID      string
+	Text    string
+	Created time.Time
+	Expires time.Time
+	Action  SIAction
+}
+
+// MarshalBinary encodes SI document as WBXML.
+func (si ServiceIndication) MarshalBinary() ([]byte, error) {
+	tok, ok := siActionTokens[si.Action]
+	if !ok {
+		return nil, fmt.Errorf("wap: invalid si action %d", si.Action)
+	}
+	out := []byte{wbxmlV12, 0x05, wbxmlUTF8, 0x00}
+	out = append(out, 0x05|tagContent, 0x06|tagContent|tagAttrs)
+	if si.Href != "" {
+		out = appendURL(out, si.Href, siHrefPrefixes, 0x0B)
+	}
+	if si.ID != "" {
+		out = appendString(append(out, 0x11), si.ID)
+	}
+	if !si.Created.IsZero() {
+		out = appendDate(append(out, 0x0A), si.Created)
+	}
+	if !si.Expires.IsZero() {
+		out = appendDate(append(out, 0x10), si.Expires)
+	}
+	if si.Action != SignalMedium {
+		out = append(out, tok)
+	}
+	out = append(out, wbxmlEnd)
+	if si.Text != "" {
+		out = appendString(out, si.Text)
+	}
+	return append(out, wbxmlEnd, wbxmlEnd), nil
+}
+
+// Push wraps SI document into push PDU.
+func (si ServiceIndication) Push(tid byte) (Push, error) {
+	body, err := si.MarshalBinary()
+	if err != nil {
+		return Push{}, err
+	}
+	return Push{
+		TransactionID: tid,
+		ContentType:   "application/vnd.wap.sic",
+		Headers:       AppIDHeader(WMLApplication),
+		Body:          body,
+	}, nil
+}
+
+// SLAction defines action attribute of Service Loading.
+type SLAction int
+
+// Service Loading actions.
+const (
+	ExecuteLow SLAction = iota // Default action, not encoded.
+	ExecuteHigh
+	Cache
+)
+
+var slActionTokens = map[SLAction]byte{
+	ExecuteLow:  0x05,
+	ExecuteHigh: 0x06,
+	Cache:       0x07,
+}
+
+var slHrefPrefixes = []urlPrefix{
+	{"http://www.", 0x0A},
+	{"http://", 0x09},
+	{"https://www.", 0x0C},
+	{"https://", 0x0B},
+}
+
+// ServiceLoading is WAP Service Loading document.
+type ServiceLoading struct {
+	Href   string
+	Action SLAction
+}
+
+// MarshalBinary encodes SL document as WBXML.
+func (sl ServiceLoading) MarshalBinary() ([]byte, error) {
+	tok, ok := slActionTokens[sl.Action]
+	if !ok {
+		return nil, fmt.Errorf("wap: invalid sl action %d", sl.Action)
+	}
+	if sl.Href == "" {
+		return nil, errors.New("wap: sl href is mandatory")
+	}
+	out := []byte{wbxmlV12, 0x06, wbxmlUTF8, 0x00, 0x05 | tagAttrs}
+	out = appendURL(out, sl.Href, slHrefPrefixes, 0x08)
+	if sl.Action != ExecuteLow {
+		out = append(out, tok)
+	}
+	return append(out, wbxmlEnd), nil
+}
+
+// Push wraps SL document into push PDU.
+func (sl ServiceLoading) Push(tid byte) (Push, error) {
+	body, err := sl.MarshalBinary()
+	if err != nil {
+		return Push{}, err
+	}
+	return Push{
+		TransactionID: tid,
+		ContentType:   "application/vnd.wap.slc",
+		Headers:       AppIDHeader(WMLApplication),
+		Body:          body,
+	}, nil
+}
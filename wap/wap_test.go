@@ -0,0 +1,124 @@
+package wap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+func toHex(s string) []byte {
+	b, _ := hex.DecodeString(strings.Replace(s, "|", "", -1))
+	return b
+}
+
+func TestServiceIndication(t *testing.T) {
+	// Example from WAP-167-ServiceInd specification.
+	si := ServiceIndication{
+		Href:    "http://www.xyz.com/email/123/abc.wml",
+		Text:    "You have 4 new emails",
+		Created: time.Date(1999, 6, 25, 15, 23, 15, 0, time.UTC),
+		Expires: time.Date(1999, 6, 30, 0, 0, 0, 0, time.UTC),
+	}
+	expected := toHex("02056A00|45|C6|0D|0378797A00|85|03656D61696C2F3132332F6162632E776D6C00|" +
+		"0AC30719990625152315|10C30419990630|01|03596F7520686176652034206E657720656D61696C7300|01|01")
+	b, err := si.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("MarshalBinary() => %X\nexpected %X", b, expected)
+	}
+}
+
+func TestServiceLoading(t *testing.T) {
+	sl := ServiceLoading{Href: "https://example.org/app", Action: Cache}
+	p, err := sl.Push(0x25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := toHex("25|06|03|B0|AF82|02066A00|85|0B|036578616D706C6500|88|0361707000|07|01")
+	if !bytes.Equal(b, expected) {
+		t.Errorf("MarshalBinary() => %X\nexpected %X", b, expected)
+	}
+}
+
+func TestPushParams(t *testing.T) {
+	p := Push{
+		TransactionID: 0x01,
+		ContentType:   "application/vnd.wap.connectivity-wbxml",
+		Params:        []byte{0x91, 0x81},
+		Body:          []byte{0xAA},
+	}
+	b, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := toHex("01|06|04|03B69181|AA"); !bytes.Equal(b, expected) {
+		t.Errorf("MarshalBinary() => %X\nexpected %X", b, expected)
+	}
+}
+
+func TestSegmentationRoundTrip(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x5A}, 300)
+	sms, err := SubmitSms(pdu.SubmitSm{DestinationAddr: "123"}, Datagram{SrcPort: WSPPort, DstPort: PushPort, Payload: payload}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sms) != 3 {
+		t.Fatalf("expected 3 segments got %d", len(sms))
+	}
+	var joined []byte
+	for i, sm := range sms {
+		if len(sm.ShortMessage) > maxUserData {
+			t.Errorf("segment %d too long %d", i, len(sm.ShortMessage))
+		}
+		if sm.DataCoding != pdu.OctetDataCoding {
+			t.Errorf("segment %d data coding 0x%02X", i, sm.DataCoding)
+		}
+		udh, _, err := sm.UDH()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c, ok := udh.Concat(); !ok || c.Seq != i+1 || c.Total != 3 || c.Ref != 7 {
+			t.Errorf("segment %d concat %+v", i, c)
+		}
+		dg, err := Decode(&pdu.DeliverSm{EsmClass: sm.EsmClass, ShortMessage: sm.ShortMessage})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dg.SrcPort != WSPPort || dg.DstPort != PushPort {
+			t.Errorf("segment %d ports %+v", i, dg)
+		}
+		joined = append(joined, dg.Payload...)
+	}
+	if !bytes.Equal(joined, payload) {
+		t.Errorf("joined payload doesn't match")
+	}
+	if _, err := Decode(&pdu.DeliverSm{ShortMessage: []byte("plain")}); err == nil {
+		t.Errorf("expected error decoding message without ports")
+	}
+}
+
+func TestDecodePortTLVs(t *testing.T) {
+	// UDH carries only concatenation, ports are in TLVs.
+	sm := &pdu.DeliverSm{
+		EsmClass:     pdu.EsmClass{Feature: pdu.UDHIEsmFeat},
+		ShortMessage: append(toHex("05|00|03|07|02|01"), "push"...),
+		Options:      pdu.NewOptions().SetSourcePort(WSPPort).SetDestinationPort(PushPort),
+	}
+	dg, err := Decode(sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dg.SrcPort != WSPPort || dg.DstPort != PushPort || string(dg.Payload) != "push" {
+		t.Errorf("unexpected datagram %+v", dg)
+	}
+}
@@ -0,0 +1,138 @@
+package smpp
+
+import (
+	"context"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+// Window lanes, requests waiting in the high lane get free slots first.
+const (
+	highLane = iota
+	normalLane
+	numLanes
+)
+
+// waiter is sender waiting for a free slot in the sending window.
+type waiter struct {
+	ready chan struct{}
+	err   error
+}
+
+// lane returns priority lane of the request. Session management requests
+// go to the high lane so they are not starved by bulk traffic.
+func lane(id pdu.CommandID) int {
+	switch id {
+	case pdu.EnquireLinkID, pdu.UnbindID, pdu.GenericNackID,
+		pdu.BindTransceiverID, pdu.BindTransmitterID, pdu.BindReceiverID:
+		return highLane
+	}
+	return normalLane
+}
+
+// freeSlots returns number of slots that are neither used nor granted.
+//
+// Must be guarded by mutex.
+func (sess *Session) freeSlots() int {
+	return sess.conf.SendWinSize - len(sess.sent) - sess.granted
+}
+
+// acquire takes slot in the sending window. If the window is full and
+// SessionConf.WindowWait is set it waits for a free slot in FIFO order
+// within the lane of the request, otherwise it returns temporary error.
+// Mutex is released while waiting.
+//
+// Must be guarded by mutex.
+func (sess *Session) acquire(ctx context.Context, id pdu.CommandID) error {
+	if sess.freeSlots() > 0 {
+		return nil
+	}
+	if !sess.conf.WindowWait {
+		return Error{Msg: "smpp: sending window closed", Temp: true}
+	}
+	l := lane(id)
+	w := &waiter{ready: make(chan struct{})}
+	sess.waiters[l] = append(sess.waiters[l], w)
+	sess.mu.Unlock()
+	select {
+	case <-w.ready:
+		sess.mu.Lock()
+	case <-ctx.Done():
+		sess.mu.Lock()
+		if sess.removeWaiter(l, w) {
+			return ctx.Err()
+		}
+		// Slot was granted in the meantime, pass it on.
+		<-w.ready
+		if w.err == nil {
+			sess.granted--
+			sess.grant()
+		}
+		return ctx.Err()
+	}
+	if w.err != nil {
+		return w.err
+	}
+	sess.granted--
+	return nil
+}
+
+// Must be guarded by mutex.
+func (sess *Session) removeWaiter(l int, w *waiter) bool {
+	for i, other := range sess.waiters[l] {
+		if other == w {
+			sess.waiters[l] = append(sess.waiters[l][:i], sess.waiters[l][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// grant hands free slots to the waiting senders.
+//
+// Must be guarded by mutex.
+func (sess *Session) grant() {
+	for l := range sess.waiters {
+		for len(sess.waiters[l]) > 0 && sess.freeSlots() > 0 {
+			w := sess.waiters[l][0]
+			sess.waiters[l][0] = nil
+			sess.waiters[l] = sess.waiters[l][1:]
+			sess.granted++
+			close(w.ready)
+		}
+	}
+}
+
+// release removes request from the sending window and hands its slot
+// to the next waiting sender.
+//
+// Must be guarded by mutex.
+func (sess *Session) release(seq uint32) {
+	delete(sess.sent, seq)
+	sess.grant()
+}
+
+// failWaiters wakes all waiting senders with the error.
+//
+// Must be guarded by mutex.
+func (sess *Session) failWaiters(err error) {
+	for l := range sess.waiters {
+		for _, w := range sess.waiters[l] {
+			w.err = err
+			close(w.ready)
+		}
+		sess.waiters[l] = nil
+	}
+}
+
+// outstanding returns number of requests waiting for the response or for
+// a slot in the sending window.
+func (sess *Session) outstanding() int {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	n := len(sess.sent) + sess.granted
+	for l := range sess.waiters {
+		n += len(sess.waiters[l])
+	}
+	return n
+}
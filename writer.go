@@ -0,0 +1,165 @@
+package smpp
+
+import (
+	"net"
+	"time"
+
+	"github.com/pentolbakso/smpp-go/pdu"
+)
+
+var errWriterClosed = Error{Msg: "smpp: session closed before writing pdu"}
+
+// outFrame is encoded PDU waiting in the outbound queue.
+type outFrame struct {
+	buf  *[]byte
+	done chan error
+	// Sequence of the request whose pending send fails if the write fails.
+	seq     uint32
+	request bool
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// enqueue encodes PDU and puts it to the outbound queue. Returned channel
+// receives the result of the write once the PDU is written to connection.
+//
+// Must be guarded by mutex.
+func (sess *Session) enqueue(p pdu.PDU, opts ...pdu.EncoderOption) (uint32, <-chan error, error) {
+	seq, f, err := sess.encode(p, opts...)
+	if err != nil {
+		return 0, nil, err
+	}
+	f.done = make(chan error, 1)
+	if err := sess.push(f); err != nil {
+		return 0, nil, err
+	}
+	return seq, f.done, nil
+}
+
+// Must be guarded by mutex.
+func (sess *Session) encode(p pdu.PDU, opts ...pdu.EncoderOption) (uint32, *outFrame, error) {
+	buf := pdu.GetBuffer()
+	b, seq, err := sess.enc.AppendTo((*buf)[:0], p, opts...)
+	*buf = b
+	if err != nil {
+		pdu.PutBuffer(buf)
+		return 0, nil, err
+	}
+	return seq, &outFrame{buf: buf, seq: seq}, nil
+}
+
+func (sess *Session) push(f *outFrame) error {
+	sess.wmu.Lock()
+	if sess.werr != nil {
+		err := sess.werr
+		sess.wmu.Unlock()
+		pdu.PutBuffer(f.buf)
+		return err
+	}
+	sess.queue = append(sess.queue, f)
+	sess.wmu.Unlock()
+	select {
+	case sess.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// writeLoop writes queued PDUs to the connection. All PDUs queued while
+// the previous write was in progress are written together so they can be
+// sent with a single system call. PDUs queued before the session is closed
+// are flushed before the loop exits.
+func (sess *Session) writeLoop() {
+	defer sess.wg.Done()
+	defer close(sess.flushed)
+	var (
+		batch []*outFrame
+		vec   net.Buffers
+	)
+	for {
+		select {
+		case <-sess.wake:
+		case <-sess.stop:
+			sess.flush()
+			return
+		}
+		sess.wmu.Lock()
+		batch, sess.queue = sess.queue, batch[:0]
+		sess.wmu.Unlock()
+		if len(batch) == 0 {
+			continue
+		}
+		vec = vec[:0]
+		for _, f := range batch {
+			vec = append(vec, *f.buf)
+		}
+		err := sess.write(vec)
+		sess.written(batch, err)
+		for i := range batch {
+			batch[i] = nil
+		}
+		if err != nil {
+			select {
+			case <-sess.stop:
+			default:
+				sess.conf.Logger.ErrorF("writing pdu: %s %+v", sess, err)
+			}
+			sess.failQueue(err)
+			sess.shutdown()
+			return
+		}
+	}
+}
+
+// written reports result of the write to the frames. Pending requests
+// which weren't written are failed with the error.
+func (sess *Session) written(frames []*outFrame, err error) {
+	for _, f := range frames {
+		if f.done != nil {
+			f.done <- err
+		}
+		if err != nil && f.request {
+			sess.failSent(f.seq, err)
+		}
+		pdu.PutBuffer(f.buf)
+	}
+}
+
+func (sess *Session) write(vec net.Buffers) error {
+	if d, ok := sess.RWC.(writeDeadliner); ok && sess.conf.WriteTimeout > 0 {
+		if err := d.SetWriteDeadline(time.Now().Add(sess.conf.WriteTimeout)); err != nil {
+			return err
+		}
+	}
+	_, err := vec.WriteTo(sess.RWC)
+	return err
+}
+
+// flush writes PDUs remaining in the queue and rejects any further ones.
+func (sess *Session) flush() {
+	sess.wmu.Lock()
+	sess.werr = errWriterClosed
+	queue := sess.queue
+	sess.queue = nil
+	sess.wmu.Unlock()
+	if len(queue) == 0 {
+		return
+	}
+	vec := make(net.Buffers, 0, len(queue))
+	for _, f := range queue {
+		vec = append(vec, *f.buf)
+	}
+	sess.written(queue, sess.write(vec))
+}
+
+// failQueue reports error to all queued PDUs and rejects any further ones.
+func (sess *Session) failQueue(err error) {
+	sess.wmu.Lock()
+	sess.werr = err
+	queue := sess.queue
+	sess.queue = nil
+	sess.wmu.Unlock()
+	sess.written(queue, err)
+}